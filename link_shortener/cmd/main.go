@@ -1,19 +1,28 @@
 package main
 
 import (
+	"context"
 	"errors"
 	mainversion "link_shortener"
 	"link_shortener/internal/config"
+	"link_shortener/internal/http-server/handlers/admin"
+	"link_shortener/internal/http-server/handlers/base"
 	"link_shortener/internal/http-server/handlers/email/info"
 	"link_shortener/internal/http-server/handlers/email/verify"
+	"link_shortener/internal/http-server/handlers/links"
 	"link_shortener/internal/http-server/handlers/system"
 	"link_shortener/internal/http-server/router"
 	"link_shortener/internal/http-server/server"
 	"link_shortener/pkg/container"
+	"link_shortener/pkg/middleware"
+	"link_shortener/pkg/ratelimit"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 )
 
 const ConfigPath = "./config/env"
@@ -27,6 +36,14 @@ func main() {
 		}
 	}()
 
+	// exitCode is applied after deferred cleanup has finished
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	log.Printf("Starting %s v%s (built: %s)", mainversion.AppName, mainversion.Version, mainversion.BuildDate)
 
 	configPath := getConfigPath()
@@ -36,24 +53,56 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize container: %v", err)
 	}
+	defer func() {
+		if err := ctr.Close(); err != nil {
+			log.Printf("Container closed with errors: %v", err)
+		}
+	}()
 
 	mux := router.NewRouter()
-	err = registerHandlers(mux, ctr, cfg.MailService)
+	err = registerHandlers(mux, ctr, cfg)
 	if err != nil {
-		ctr.Logger.Error("Failed to register handlers: %v", err)
+		ctr.Logger.Error("Failed to register handlers", "error", err)
+		exitCode = 1
 		return
 	}
 
-	srv := server.New(cfg.HttpServer.Port, mux)
+	// every request gets ID and logger scoped to it before reaching handlers,
+	// metrics wrap mux directly to see matched route
+	handler := middleware.Chain(middleware.RequestID(ctr.Logger), middleware.Metrics(ctr.Metrics))(mux)
+	srv := server.New(cfg.HttpServer, handler)
 
-	ctr.Logger.Info("Starting server",
-		"port", cfg.HttpServer.Port,
-		"env", cfg.Env)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		ctr.Logger.Error("Server failed to start", "error", err)
-		os.Exit(1)
+	serverErr := make(chan error, 1)
+	go func() {
+		ctr.Logger.Info("Starting server",
+			"port", cfg.HttpServer.Port,
+			"env", cfg.Env)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ctr.Logger.Error("Server failed to start", "error", err)
+			exitCode = 1
+		}
+		return
+	case <-ctx.Done():
+		stop()
+		ctr.Logger.Info("Shutdown signal received, draining connections",
+			"timeout", cfg.HttpServer.ShutdownTimeout)
 	}
+
+	if err := srv.Shutdown(cfg.HttpServer.ShutdownTimeout); err != nil {
+		ctr.Logger.Error("Server shutdown failed", "error", err)
+		exitCode = 1
+		return
+	}
+
+	ctr.Logger.Info("Server stopped")
 }
 
 func getConfigPath() string {
@@ -63,21 +112,95 @@ func getConfigPath() string {
 	return filepath.Join(ConfigPath, DevFile)
 }
 
-func registerHandlers(mux *http.ServeMux, ctr *container.Container, cfg config.MailService) error {
-	err := verify.New(mux, ctr.Logger, ctr.EmailService, ctr.HashService, ctr.Storage, ctr.Validator)
+func registerHandlers(mux *http.ServeMux, ctr *container.Container, cfg *config.Config) error {
+	err := verify.New(mux, ctr.Logger, ctr.EmailService, ctr.HashService, ctr.Storage, ctr.Validator, ctr.LinkBuilder,
+		ctr.Owners, ctr.Addresses, ctr.Verified, verify.Options{
+			ResendCooldown: cfg.Verification.ResendCooldown,
+			TrustProxy:     cfg.HttpServer.TrustProxy,
+			ExposeLink:     cfg.Env.IsDev(),
+
+			SendMiddlewares: sendRateLimits(ctr, cfg.RateLimit, cfg.HttpServer.TrustProxy),
+		})
 	if err != nil {
 		ctr.Logger.Error("Failed to register verification handler:", "error", err)
 		return err
 	}
 
-	err = info.New(mux, ctr.Logger, cfg.Name, cfg.Host, cfg.Port)
+	err = links.New(mux, ctr.Logger, ctr.LinkStorage, ctr.CodeService, ctr.Validator, ctr.LinkBuilder,
+		ctr.Analytics, ctr.Owners, ctr.URLPolicy, links.Options{
+			Domains:      cfg.Links.Domains,
+			AnonymousTTL: cfg.Links.AnonymousTTL,
+			BulkTimeout:  cfg.Links.BulkTimeout,
+
+			UnlockMiddlewares: unlockRateLimits(ctr, cfg.RateLimit, cfg.HttpServer.TrustProxy),
+		})
+	if err != nil {
+		ctr.Logger.Error("Failed to register links handler:", "error", err)
+		return err
+	}
+
+	err = info.New(mux, ctr.Logger, cfg.MailService.Name, cfg.MailService.Host, cfg.MailService.Port)
 	if err != nil {
 		ctr.Logger.Error("Failed to register info handler:", "error", err)
 		return err
 	}
 
-	system.New(mux, ctr.Logger)
+	if cfg.Admin.Token == "" {
+		ctr.Logger.Warn("Admin routes disabled, admin token is not configured")
+	} else if err = admin.New(mux, ctr.Logger, ctr.Verified, ctr.Addresses, cfg.Admin.Token); err != nil {
+		ctr.Logger.Error("Failed to register admin handler:", "error", err)
+		return err
+	}
+
+	system.New(mux, ctr.Logger, system.Options{
+		MailQueue: ctr.MailQueue,
+		Clicks:    ctr.Analytics,
+		LinkCache: ctr.LinkCache,
+		Readiness: ctr.Readiness,
+		Metrics:   ctr.Metrics.Handler(),
+	})
 
 	ctr.Logger.Debug("All handlers registered successfully")
 	return nil
 }
+
+// unlockRateLimits limits password attempts on protected links per client IP
+func unlockRateLimits(ctr *container.Container, cfg config.RateLimit, trustProxy bool) []middleware.Middleware {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	writer := &base.Handler{Logger: ctr.Logger}
+	ipLimit := ratelimit.Limit{Requests: cfg.IPRequests, Period: cfg.IPPeriod}
+
+	return []middleware.Middleware{
+		middleware.RateLimitMiddleware("unlock", ctr.RateLimits, ipLimit,
+			middleware.ClientIPKey(trustProxy), writer),
+	}
+}
+
+// sendRateLimits limits email sending routes per client IP and per recipient
+func sendRateLimits(ctr *container.Container, cfg config.RateLimit, trustProxy bool) []middleware.Middleware {
+	if !cfg.Enabled {
+		ctr.Logger.Warn("Rate limiting of send routes disabled")
+		return nil
+	}
+
+	writer := &base.Handler{Logger: ctr.Logger}
+	ipLimit := ratelimit.Limit{Requests: cfg.IPRequests, Period: cfg.IPPeriod}
+	emailLimit := ratelimit.Limit{Requests: cfg.EmailRequests, Period: cfg.EmailPeriod}
+	// invalid addresses are rejected by handler, they are still limited as sent
+	normalize := func(email string) string {
+		if mailbox, err := ctr.Addresses.Mailbox(email); err == nil {
+			return mailbox
+		}
+		return strings.ToLower(strings.TrimSpace(email))
+	}
+
+	return []middleware.Middleware{
+		middleware.RateLimitMiddleware("ip", ctr.RateLimits, ipLimit,
+			middleware.ClientIPKey(trustProxy), writer),
+		middleware.RateLimitMiddleware("email", ctr.RateLimits, emailLimit,
+			middleware.JSONEmailKey(normalize), writer),
+	}
+}
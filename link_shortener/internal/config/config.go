@@ -37,6 +37,22 @@ func (e Environment) IsTest() bool {
 	return e == EnvTest
 }
 
+const (
+	MailTransportSMTP    = "smtp"
+	MailTransportMailHog = "mailhog"
+	MailTransportFile    = "file"
+	MailTransportMemory  = "memory"
+)
+
+const (
+	// MailTLSOpportunistic upgrades SMTP connection when server offers STARTTLS
+	MailTLSOpportunistic = "opportunistic"
+	// MailTLSStartTLS refuses to send unless STARTTLS upgrade succeeds
+	MailTLSStartTLS = "starttls"
+	// MailTLSImplicit connects over TLS from the start, usually on port 465
+	MailTLSImplicit = "tls"
+)
+
 type MailService struct {
 	Name     string `yaml:"name" env:"MAIL_NAME" env-required:"true"`
 	Email    string `yaml:"email" env:"MAIL_EMAIL" env-required:"true"`
@@ -45,6 +61,63 @@ type MailService struct {
 	Host     string `yaml:"host" env:"MAIL_HOST" env-required:"true"`
 	Port     string `yaml:"port" env:"MAIL_PORT"`
 	Address  string `yaml:"address" env:"MAIL_ADDRESS"`
+	// TemplatesDir holds <locale>/<name>.<subject|txt|html>.tmpl files overriding embedded ones
+	TemplatesDir  string `yaml:"templates_dir" env:"MAIL_TEMPLATES_DIR"`
+	DefaultLocale string `yaml:"default_locale" env:"MAIL_DEFAULT_LOCALE" env-default:"en"`
+	// Transport is smtp, mailhog, file or memory, empty picks mailhog when
+	// Name is mailhog and smtp otherwise
+	Transport string `yaml:"transport" env:"MAIL_TRANSPORT"`
+	// TLS is SMTP transport security: opportunistic, starttls or tls
+	TLS string `yaml:"tls" env:"MAIL_TLS" env-default:"opportunistic"`
+	// Dir receives .eml files of file transport
+	Dir string `yaml:"dir" env:"MAIL_DIR" env-default:"./tmp/mail/outbox"`
+}
+
+// EmailPolicy screens recipient addresses before verification emails are sent
+type EmailPolicy struct {
+	// FoldGmail treats Gmail addresses differing in dots or +tags as one mailbox
+	FoldGmail bool `yaml:"fold_gmail" env:"EMAIL_POLICY_FOLD_GMAIL" env-default:"false"`
+	// DisposablePath is file of disposable mailbox domains, one per line,
+	// empty disables it
+	DisposablePath string `yaml:"disposable_path" env:"EMAIL_POLICY_DISPOSABLE_PATH"`
+	// DisposableReload is how often disposable domains file is checked for changes
+	DisposableReload time.Duration `yaml:"disposable_reload" env:"EMAIL_POLICY_DISPOSABLE_RELOAD" env-default:"30s"`
+	// CheckMX rejects domains which do not exist or declare they accept no mail
+	CheckMX bool `yaml:"check_mx" env:"EMAIL_POLICY_CHECK_MX" env-default:"false"`
+}
+
+// MailQueue configures background delivery of outbound emails
+type MailQueue struct {
+	Workers       int           `yaml:"workers" env:"MAIL_QUEUE_WORKERS" env-default:"4"`
+	Size          int           `yaml:"size" env:"MAIL_QUEUE_SIZE" env-default:"100"`
+	MaxAttempts   int           `yaml:"max_attempts" env:"MAIL_QUEUE_MAX_ATTEMPTS" env-default:"5"`
+	BaseBackoff   time.Duration `yaml:"base_backoff" env:"MAIL_QUEUE_BASE_BACKOFF" env-default:"1s"`
+	MaxBackoff    time.Duration `yaml:"max_backoff" env:"MAIL_QUEUE_MAX_BACKOFF" env-default:"1m"`
+	DrainTimeout  time.Duration `yaml:"drain_timeout" env:"MAIL_QUEUE_DRAIN_TIMEOUT" env-default:"10s"`
+	DeadLetterDir string        `yaml:"dead_letter_dir" env:"MAIL_QUEUE_DEAD_LETTER_DIR" env-default:"./tmp/mail"`
+}
+
+// RateLimit bounds verification emails sent per client IP and per recipient,
+// each key may spend Requests within Period
+type RateLimit struct {
+	Enabled       bool          `yaml:"enabled" env:"RATE_LIMIT_ENABLED" env-default:"true"`
+	IPRequests    int           `yaml:"ip_requests" env:"RATE_LIMIT_IP_REQUESTS" env-default:"10"`
+	IPPeriod      time.Duration `yaml:"ip_period" env:"RATE_LIMIT_IP_PERIOD" env-default:"1m"`
+	EmailRequests int           `yaml:"email_requests" env:"RATE_LIMIT_EMAIL_REQUESTS" env-default:"3"`
+	EmailPeriod   time.Duration `yaml:"email_period" env:"RATE_LIMIT_EMAIL_PERIOD" env-default:"1h"`
+}
+
+// Analytics configures click recording of short link redirects
+type Analytics struct {
+	Enabled       bool          `yaml:"enabled" env:"ANALYTICS_ENABLED" env-default:"true"`
+	BufferSize    int           `yaml:"buffer_size" env:"ANALYTICS_BUFFER_SIZE" env-default:"1000"`
+	BatchSize     int           `yaml:"batch_size" env:"ANALYTICS_BATCH_SIZE" env-default:"100"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"ANALYTICS_FLUSH_INTERVAL" env-default:"5s"`
+	FlushTimeout  time.Duration `yaml:"flush_timeout" env:"ANALYTICS_FLUSH_TIMEOUT" env-default:"5s"`
+	// GeoIPPath points to MaxMind Country/City database, empty disables countries
+	GeoIPPath string `yaml:"geoip_path" env:"ANALYTICS_GEOIP_PATH"`
+	// IPSalt keeps visitor hashes stable across restarts, random when empty
+	IPSalt string `yaml:"ip_salt" env:"ANALYTICS_IP_SALT"`
 }
 
 type HttpServer struct {
@@ -54,14 +127,115 @@ type HttpServer struct {
 	Address     string        `yaml:"address" env:"HTTP_ADDRESS"`
 	Timeout     time.Duration `yaml:"timeout" env:"HTTP_TIMEOUT" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" env-default:"60s"`
+	// ReadHeaderTimeout protects from slow clients holding connections open
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" env-default:"2s"`
+	// ShutdownTimeout is how long in-flight requests may drain on SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT" env-default:"10s"`
+	// ExternalURL is public origin when service runs behind reverse proxy
+	ExternalURL string `yaml:"external_url" env:"HTTP_EXTERNAL_URL"`
+	// PathPrefix is prepended to paths of generated public links
+	PathPrefix string `yaml:"path_prefix" env:"HTTP_PATH_PREFIX"`
+	// TrustProxy takes client IP from the last X-Forwarded-For entry, enable
+	// only behind single proxy which appends to the header
+	TrustProxy bool `yaml:"trust_proxy" env:"HTTP_TRUST_PROXY" env-default:"false"`
 }
 
+const (
+	VerificationStored    = "stored"
+	VerificationStateless = "stateless"
+)
+
+// Verification controls how verification links are issued and how long they live.
+// Stored mode keeps random hashes in storage, stateless mode issues tokens
+// signed with Secret which are checked without storage
+type Verification struct {
+	Mode          string        `yaml:"mode" env:"VERIFICATION_MODE" env-default:"stored"`
+	Secret        string        `yaml:"secret" env:"VERIFICATION_SECRET"`
+	TTL           time.Duration `yaml:"ttl" env:"VERIFICATION_TTL" env-default:"24h"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"VERIFICATION_SWEEP_INTERVAL" env-default:"10m"`
+	// ResendCooldown is minimal delay between two emails of the same pending verification
+	ResendCooldown time.Duration `yaml:"resend_cooldown" env:"VERIFICATION_RESEND_COOLDOWN" env-default:"1m"`
+}
+
+func (v Verification) IsStateless() bool {
+	return v.Mode == VerificationStateless
+}
+
+const (
+	StorageLocal    = "local"
+	StoragePostgres = "postgres"
+)
+
+// Links configures short link creation
+type Links struct {
+	// Domains are vanity hosts served besides the default one, links created
+	// for a domain resolve only when requested through it
+	Domains []string `yaml:"domains" env:"LINKS_DOMAINS" env-separator:","`
+	// Reserved words extend built-in list of aliases that would shadow service routes
+	Reserved []string `yaml:"reserved" env:"LINKS_RESERVED" env-separator:","`
+	// AnonymousTTL is lifetime of links created without owner API key
+	AnonymousTTL time.Duration `yaml:"anonymous_ttl" env:"LINKS_ANONYMOUS_TTL" env-default:"24h"`
+	// BulkTimeout replaces server read and write deadlines of import and
+	// export, which move far more data than regular requests
+	BulkTimeout time.Duration `yaml:"bulk_timeout" env:"LINKS_BULK_TIMEOUT" env-default:"5m"`
+}
+
+// URLPolicy screens target URLs of links before they are saved
+type URLPolicy struct {
+	Schemes []string `yaml:"schemes" env:"URL_POLICY_SCHEMES" env-separator:"," env-default:"http,https"`
+	// BlockPrivate rejects loopback, private and link-local targets
+	BlockPrivate bool `yaml:"block_private" env:"URL_POLICY_BLOCK_PRIVATE" env-default:"true"`
+	// ResolveHosts makes BlockPrivate check addresses host names resolve to
+	ResolveHosts bool `yaml:"resolve_hosts" env:"URL_POLICY_RESOLVE_HOSTS" env-default:"false"`
+	// BlocklistPath is file of blocked domains, one per line, empty disables it
+	BlocklistPath string `yaml:"blocklist_path" env:"URL_POLICY_BLOCKLIST_PATH"`
+	// BlocklistReload is how often blocklist file is checked for changes
+	BlocklistReload time.Duration `yaml:"blocklist_reload" env:"URL_POLICY_BLOCKLIST_RELOAD" env-default:"30s"`
+}
+
+// Health configures readiness probes of dependencies
+type Health struct {
+	// Timeout bounds every single check
+	Timeout time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT" env-default:"2s"`
+	// MailCritical makes unreachable mail server fail readiness, otherwise
+	// it only degrades it as emails wait in queue
+	MailCritical bool `yaml:"mail_critical" env:"HEALTH_MAIL_CRITICAL" env-default:"false"`
+}
+
+// Admin protects administrative routes with bearer token, empty Token
+// leaves them unregistered
+type Admin struct {
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+}
+
+// LinkCache keeps recent link lookups in memory to speed up redirects
+type LinkCache struct {
+	// Size bounds number of cached lookups, zero disables cache
+	Size int `yaml:"size" env:"LINK_CACHE_SIZE" env-default:"10000"`
+	// TTL bounds how long links changed by other instances may be served stale
+	TTL time.Duration `yaml:"ttl" env:"LINK_CACHE_TTL" env-default:"1m"`
+	// NegativeTTL is how long unknown codes are remembered as missing
+	NegativeTTL time.Duration `yaml:"negative_ttl" env:"LINK_CACHE_NEGATIVE_TTL" env-default:"10s"`
+}
+
+type Storage struct {
+	Type string `yaml:"type" env:"STORAGE_TYPE" env-default:"local"`
+	// Fsync makes local storage flush every write to disk, trading latency for durability
+	Fsync bool `yaml:"fsync" env:"STORAGE_FSYNC" env-default:"true"`
+}
+
+func (s Storage) IsPostgres() bool {
+	return s.Type == StoragePostgres
+}
+
+// Database is only read when [Storage] type is postgres, defaults
+// match link_shortener_postgres from docker-compose
 type Database struct {
 	Host     string `yaml:"host" env:"DB_HOST" env-default:"localhost"`
 	Port     string `yaml:"port" env:"DB_PORT" env-default:"5432"`
-	User     string `yaml:"user" env:"DB_USER" env-required:"true"`
-	Password string `yaml:"password" env:"DB_PASSWORD" env-required:"true"`
-	Name     string `yaml:"name" env:"DB_NAME" env-required:"true"`
+	User     string `yaml:"user" env:"DB_USER" env-default:"postgres"`
+	Password string `yaml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" env:"DB_NAME" env-default:"link_shortener"`
 	SSLMode  string `yaml:"ssl_mode" env:"DB_SSL_MODE" env-default:"disable"`
 }
 
@@ -72,10 +246,21 @@ func (d Database) PsqlDSN() string {
 }
 
 type Config struct {
-	Env         Environment `yaml:"env" env:"APP_ENV" env-required:"true"`
-	MailService MailService `yaml:"mail_service"`
-	HttpServer  HttpServer  `yaml:"http"`
-	//Database    Database    `yaml:"database"`
+	Env          Environment  `yaml:"env" env:"APP_ENV" env-required:"true"`
+	MailService  MailService  `yaml:"mail_service"`
+	MailQueue    MailQueue    `yaml:"mail_queue"`
+	EmailPolicy  EmailPolicy  `yaml:"email_policy"`
+	RateLimit    RateLimit    `yaml:"rate_limit"`
+	Analytics    Analytics    `yaml:"analytics"`
+	HttpServer   HttpServer   `yaml:"http"`
+	Verification Verification `yaml:"verification"`
+	Links        Links        `yaml:"links"`
+	URLPolicy    URLPolicy    `yaml:"url_policy"`
+	LinkCache    LinkCache    `yaml:"link_cache"`
+	Health       Health       `yaml:"health"`
+	Admin        Admin        `yaml:"admin"`
+	Storage      Storage      `yaml:"storage"`
+	Database     Database     `yaml:"database"`
 }
 
 func MustLoadConfig(configPath string) *Config {
@@ -101,6 +286,10 @@ func loadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -110,5 +299,19 @@ func loadFromEnv() (*Config, error) {
 		return nil, fmt.Errorf("failed to read environment variables: %w", err)
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
+
+// validate rejects settings which read fine but are unsafe to run with
+func (c *Config) validate() error {
+	// verification links, signed tokens above all, can not be revoked
+	// and must not stay usable forever
+	if c.Verification.TTL <= 0 {
+		return fmt.Errorf("verification ttl must be positive, got %s", c.Verification.TTL)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"link_shortener/internal/http-server/handlers/base"
+	"link_shortener/pkg/errors"
+	l "link_shortener/pkg/logger"
+	"link_shortener/pkg/middleware"
+	"link_shortener/pkg/storage"
+	"net/http"
+	"strconv"
+)
+
+const (
+	V1VERIFIED      = "/api/v1/admin/verified-emails"
+	V1VERIFIEDEMAIL = "/api/v1/admin/verified-emails/{email}"
+
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+type Handler struct {
+	base.Handler
+	verified  Registry
+	addresses Addresses
+	// auth wraps every admin route
+	auth middleware.Middleware
+}
+
+// Registry lists emails which completed verification
+type Registry interface {
+	Lookup(ctx context.Context, email string) (storage.VerifiedEmail, error)
+	List(ctx context.Context, offset, limit int) ([]storage.VerifiedEmail, error)
+}
+
+// Addresses brings email to the mailbox form it is registered under
+type Addresses interface {
+	Mailbox(raw string) (string, error)
+}
+
+type VerifiedListResponse struct {
+	Emails []storage.VerifiedEmail `json:"emails"`
+	Offset int                     `json:"offset"`
+	Limit  int                     `json:"limit"`
+}
+
+// New registers admin routes guarded by bearer token, empty token is refused
+// so routes are never exposed unprotected
+func New(mux *http.ServeMux, logger l.Logger, verified Registry, addresses Addresses, token string) error {
+	if token == "" {
+		return errors.NewStructValidationError("admin token required")
+	}
+	if verified == nil || addresses == nil {
+		return errors.NewStructValidationError("registry and address normalizer required")
+	}
+
+	handler := &Handler{
+		Handler:   base.Handler{Logger: logger},
+		verified:  verified,
+		addresses: addresses,
+	}
+	handler.auth = middleware.BearerToken(token, &handler.Handler)
+
+	handler.registerRoutes(mux)
+
+	handler.Logger.Debug("admin handler created and routes registered")
+
+	return nil
+}
+
+func (h *Handler) registerRoutes(router *http.ServeMux) {
+	router.Handle("GET "+V1VERIFIED, h.auth(http.HandlerFunc(h.ListVerified)))
+	router.Handle("GET "+V1VERIFIEDEMAIL, h.auth(http.HandlerFunc(h.GetVerified)))
+}
+
+// ListVerified returns page of verified emails ordered by address
+func (h *Handler) ListVerified(w http.ResponseWriter, r *http.Request) {
+	offset, limit, err := pageParams(r)
+	if err != nil {
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	emails, err := h.verified.List(r.Context(), offset, limit)
+	if err != nil {
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, VerifiedListResponse{
+		Emails: emails,
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// GetVerified returns registry entry of email, 404 when it was never verified
+func (h *Handler) GetVerified(w http.ResponseWriter, r *http.Request) {
+	email, err := h.addresses.Mailbox(r.PathValue("email"))
+	if err != nil {
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	verified, err := h.verified.Lookup(r.Context(), email)
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Email is not verified"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, verified)
+}
+
+// pageParams reads offset and limit query parameters, limit defaults
+// to [defaultPageSize] and is capped by [maxPageSize]
+func pageParams(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+	offset, limit := 0, defaultPageSize
+
+	for name, target := range map[string]*int{"offset": &offset, "limit": &limit} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return 0, 0, fmt.Errorf("invalid %s: %s", name, raw)
+		}
+		*target = value
+	}
+
+	switch {
+	case limit == 0:
+		limit = defaultPageSize
+	case limit > maxPageSize:
+		limit = maxPageSize
+	}
+
+	return offset, limit, nil
+}
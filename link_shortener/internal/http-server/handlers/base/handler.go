@@ -11,6 +11,11 @@ type Handler struct {
 	Logger logger.Logger
 }
 
+// scopedWriter is response writer carrying logger of its request
+type scopedWriter interface {
+	Logger() logger.Logger
+}
+
 type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
@@ -33,7 +38,7 @@ func (h *Handler) WriteJSON(w http.ResponseWriter, status int, data any) {
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.Logger.Error(errors.NewJsonParseError("").Error())
+		h.writerLog(w).Error(errors.NewJsonParseError("").Error())
 	}
 }
 
@@ -56,8 +61,6 @@ func (h *Handler) WriteError(w http.ResponseWriter, err error) {
 		}
 	}
 
-	h.Logger.Debug("app error struct:")
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
@@ -67,10 +70,30 @@ func (h *Handler) WriteError(w http.ResponseWriter, err error) {
 	}
 
 	if encodeErr := json.NewEncoder(w).Encode(response); encodeErr != nil {
-		h.Logger.Error("Failed to encode error response", "error", encodeErr)
+		h.writerLog(w).Error("Failed to encode error response", "error", encodeErr)
 	}
 
-	h.Logger.Error("HTTP error", "status", status, "error", err)
+	h.writerLog(w).Error("HTTP error", "status", status, "error", err)
+}
+
+// Log returns logger scoped to request by request ID middleware, handler
+// logger is used for requests outside of it
+func (h *Handler) Log(r *http.Request) logger.Logger {
+	return logger.FromContext(r.Context(), h.Logger)
+}
+
+// writerLog finds logger of request among writers wrapping w
+func (h *Handler) writerLog(w http.ResponseWriter) logger.Logger {
+	for {
+		if scoped, ok := w.(scopedWriter); ok {
+			return scoped.Logger()
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return h.Logger
+		}
+		w = wrapper.Unwrap()
+	}
 }
 
 func (h *Handler) ParseJSON(r *http.Request, v any) error {
@@ -39,7 +39,7 @@ func (h *Handler) emailInfo(w http.ResponseWriter, r *http.Request) {
 	var req map[string]any
 	err := h.ParseJSON(r, &req)
 	if err != nil {
-		h.Logger.Error(errors.NewJsonParseError(err.Error()).Error())
+		h.Log(r).Error(errors.NewJsonParseError(err.Error()).Error())
 		return
 	}
 
@@ -51,5 +51,5 @@ func (h *Handler) emailInfo(w http.ResponseWriter, r *http.Request) {
 		"port":     h.port,
 	}
 	h.Handler.WriteJSON(w, http.StatusOK, payload)
-	h.Logger.Info("Email info", "email=", h.email, "host=", h.host, "port=", h.port)
+	h.Log(r).Info("Email info", "email=", h.email, "host=", h.host, "port=", h.port)
 }
@@ -0,0 +1,44 @@
+package verify
+
+import "sync"
+
+// emailLocks serializes changes of verification state of one email within
+// this instance, so concurrent send, resend and verify of the same address
+// do not interleave between reading index and saving it
+type emailLocks struct {
+	mu    sync.Mutex
+	locks map[string]*emailLock
+}
+
+type emailLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newEmailLocks() *emailLocks {
+	return &emailLocks{locks: make(map[string]*emailLock)}
+}
+
+// lock acquires lock of email and returns function releasing it
+func (e *emailLocks) lock(email string) func() {
+	e.mu.Lock()
+	l, ok := e.locks[email]
+	if !ok {
+		l = &emailLock{}
+		e.locks[email] = l
+	}
+	l.refs++
+	e.mu.Unlock()
+
+	l.Lock()
+
+	return func() {
+		l.Unlock()
+		e.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(e.locks, email)
+		}
+		e.mu.Unlock()
+	}
+}
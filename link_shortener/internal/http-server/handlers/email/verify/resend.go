@@ -0,0 +1,254 @@
+package verify
+
+import (
+	"context"
+	stdErrors "errors"
+	"link_shortener/pkg/errors"
+	pkgHttp "link_shortener/pkg/http"
+	l "link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Verification states reported by status route
+const (
+	StatusPending  = "pending"
+	StatusVerified = "verified"
+	StatusExpired  = "expired"
+)
+
+// StatusResponse describes latest verification of email. ResendAt is
+// the earliest time verification email may be resent
+type StatusResponse struct {
+	Email      string    `json:"email"`
+	Status     string    `json:"status"`
+	SentAt     time.Time `json:"sent_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+	VerifiedAt time.Time `json:"verified_at,omitzero"`
+	ResendAt   time.Time `json:"resend_available_at,omitzero"`
+}
+
+// ResendVerification emails pending verification of address again. Link
+// still valid is reused, expired one is rotated to a fresh hash. Repeated
+// resends within cooldown are rejected with Retry-After
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req SendRequest
+	if err := h.ParseJSON(r, &req); err != nil {
+		h.Log(r).Error(errors.Wrap("invalid request", err).Error())
+		h.WriteError(w, errors.NewJsonParseError(err.Error()))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		h.Log(r).Error(errors.NewStructValidationError(err.Error()).Error())
+		h.WriteError(w, errors.NewStructValidationError(err.Error()))
+		return
+	}
+
+	email, err := h.addresses.Check(r.Context(), req.Email)
+	if err != nil {
+		h.Log(r).Warn("Email address rejected", "email", req.Email, "reason", err)
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	// cooldown check, send and index update form one step per mailbox
+	mailbox := h.mailbox(email)
+	unlock := h.locks.lock(mailbox)
+	defer unlock()
+
+	index, ok := h.loadIndex(w, r, mailbox)
+	if !ok {
+		return
+	}
+
+	if index.Verified() {
+		h.WriteError(w, errors.NewConflictError("Email is already verified"))
+		return
+	}
+
+	now := time.Now().UTC()
+	if wait := index.SentAt.Add(h.resendCooldown).Sub(now); wait > 0 {
+		seconds := int(wait.Round(time.Second) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set(pkgHttp.RetryAfterHeader, strconv.Itoa(seconds))
+		h.WriteError(w, errors.NewRateLimitError("Verification email was sent recently, retry later"))
+		return
+	}
+
+	hash := index.Hash
+	rotated := false
+	if _, pending := h.pending(r.Context(), hash, now); !pending {
+		hash = h.hashService.GetHash(email)
+		if err := h.save(r.Context(), email, hash); err != nil {
+			h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+			h.WriteError(w, errors.NewStorageError(err.Error()))
+			return
+		}
+		rotated = true
+	}
+
+	verificationLink := h.links.Build("verify", hash)
+	if err := h.emailService.SendVerificationEmail(email, verificationLink, r.Header.Get("Accept-Language")); err != nil {
+		h.Log(r).Error(errors.NewEmailSendingError(err.Error()).Error())
+		if rotated {
+			if delErr := h.delete(r.Context(), hash); delErr != nil {
+				h.Log(r).Warn("Failed to delete verification record", "hash", hash, "error", delErr)
+			}
+		}
+		h.WriteError(w, errors.NewEmailSendingError(err.Error()))
+		return
+	}
+
+	if rotated {
+		h.discard(r.Context(), index.Hash)
+	}
+
+	index.Hash = hash
+	index.SentAt = now
+	index.Resends++
+	if err := h.storage.SaveEmailIndex(r.Context(), index); err != nil {
+		h.Log(r).Warn("Failed to update email index", "email", mailbox, "error", err)
+	}
+
+	// link is never returned, it would let anyone verify any pending address
+	response := SendResponse{Message: "Verification email queued for delivery"}
+
+	h.WriteJSON(w, http.StatusOK, response)
+	h.Log(r).Info("Verification email resent", "email", email, "rotated", rotated)
+}
+
+// VerificationStatus reports whether latest verification of email is
+// pending, verified or expired
+func (h *Handler) VerificationStatus(w http.ResponseWriter, r *http.Request) {
+	req := SendRequest{Email: r.URL.Query().Get("email")}
+	if err := h.validator.Validate(req); err != nil {
+		h.Log(r).Error(errors.NewStructValidationError(err.Error()).Error())
+		h.WriteError(w, errors.NewStructValidationError(err.Error()))
+		return
+	}
+
+	email, err := h.addresses.Check(r.Context(), req.Email)
+	if err != nil {
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	index, ok := h.loadIndex(w, r, h.mailbox(email))
+	if !ok {
+		return
+	}
+
+	response := StatusResponse{
+		Email:      index.Email,
+		Status:     StatusVerified,
+		SentAt:     index.SentAt,
+		VerifiedAt: index.VerifiedAt,
+	}
+
+	if !index.Verified() {
+		now := time.Now().UTC()
+		details, pending := h.pending(r.Context(), index.Hash, now)
+		response.Status = StatusExpired
+		if pending {
+			response.Status = StatusPending
+		}
+		if expiresAt, err := time.Parse(time.RFC3339, details[storage.KeyExpiresAt]); err == nil {
+			response.ExpiresAt = expiresAt
+		}
+		response.ResendAt = index.SentAt.Add(h.resendCooldown)
+	}
+
+	h.WriteJSON(w, http.StatusOK, response)
+}
+
+// loadIndex finds index entry of mailbox, writing error response when there is none
+func (h *Handler) loadIndex(w http.ResponseWriter, r *http.Request, mailbox string) (storage.EmailIndex, bool) {
+	index, err := h.storage.LoadEmailIndex(r.Context(), mailbox)
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("No verification requested for email"))
+			return storage.EmailIndex{}, false
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return storage.EmailIndex{}, false
+	}
+	return index, true
+}
+
+// pending loads verification record of hash and reports whether it can
+// still be verified, details are returned even for expired record
+func (h *Handler) pending(ctx context.Context, hash string, now time.Time) (map[string]string, bool) {
+	details, err := h.load(ctx, hash)
+	if err != nil {
+		return nil, false
+	}
+	return details, validateRequest(hash, details[storage.KeyHash]) && !storage.Expired(details, now)
+}
+
+// track points index of mailbox to hash just sent, record of previously
+// sent hash is dropped so only the latest link stays valid. Earlier
+// verification is kept, sending new link does not unverify email
+func (h *Handler) track(ctx context.Context, email, hash string) {
+	if h.storage == nil {
+		return
+	}
+
+	log := l.FromContext(ctx, h.Logger)
+	index := storage.EmailIndex{
+		Email:  email,
+		Hash:   hash,
+		SentAt: time.Now().UTC(),
+	}
+	if previous, err := h.storage.LoadEmailIndex(ctx, email); err == nil {
+		if previous.Hash != hash {
+			h.discard(ctx, previous.Hash)
+		}
+		index.VerifiedAt = previous.VerifiedAt
+	}
+	if err := h.storage.SaveEmailIndex(ctx, index); err != nil {
+		log.Warn("Failed to update email index", "email", email, "error", err)
+	}
+}
+
+// markVerified records verification in index of mailbox. Hash may be
+// older than the one index points to, e.g. signed token sent before
+// the latest, email is verified either way
+func (h *Handler) markVerified(ctx context.Context, email, hash string) {
+	if h.storage == nil {
+		return
+	}
+
+	unlock := h.locks.lock(email)
+	defer unlock()
+
+	log := l.FromContext(ctx, h.Logger)
+	index, err := h.storage.LoadEmailIndex(ctx, email)
+	switch {
+	case stdErrors.Is(err, storage.ErrNotFound):
+		index = storage.EmailIndex{Email: email, Hash: hash}
+	case err != nil:
+		log.Warn("Failed to load email index", "email", email, "error", err)
+		return
+	}
+
+	index.VerifiedAt = time.Now().UTC()
+	if index.SentAt.IsZero() {
+		index.SentAt = index.VerifiedAt
+	}
+	if err = h.storage.SaveEmailIndex(ctx, index); err != nil {
+		log.Warn("Failed to update email index", "email", email, "error", err)
+	}
+}
+
+// discard deletes superseded verification record, missing record is fine
+func (h *Handler) discard(ctx context.Context, hash string) {
+	if err := h.delete(ctx, hash); err != nil && !stdErrors.Is(err, storage.ErrNotFound) {
+		l.FromContext(ctx, h.Logger).Warn("Failed to delete superseded verification record", "hash", hash, "error", err)
+	}
+}
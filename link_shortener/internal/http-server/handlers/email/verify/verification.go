@@ -1,69 +1,149 @@
 package verify
 
 import (
-	"fmt"
+	"context"
 	"link_shortener/internal/http-server/handlers/base"
 	"link_shortener/pkg/errors"
 	l "link_shortener/pkg/logger"
+	"link_shortener/pkg/middleware"
+	"link_shortener/pkg/storage"
 	"net/http"
+	"time"
 )
 
 const (
 	V1SEND   = "/api/v1/send"
 	V1VERIFY = "/api/v1/verify/{hash}"
+	V1RESEND = "/api/v1/verify/resend"
+	V1STATUS = "/api/v1/verify/status"
 	SEND     = "/send"
 	VERIFY   = "/verify/{hash}"
+	RESEND   = "/verify/resend"
+	STATUS   = "/verify/status"
 )
 
 type Handler struct {
 	base.Handler `validate:"required"`
 	emailService EmailService `validate:"required"`
 	hashService  HashService  `validate:"required"`
-	storage      Storage      `validate:"required"`
-	validator    Validator    `validate:"required"`
+	storage      Storage
+	verifier     Verifier
+	validator    Validator   `validate:"required"`
+	links        LinkBuilder `validate:"required"`
+	owners       Owners      `validate:"required"`
+	addresses    Addresses   `validate:"required"`
+	verified     Registry    `validate:"required"`
+	// resendCooldown is minimal delay between emails of one pending verification
+	resendCooldown time.Duration
+	trustProxy     bool
+	exposeLink     bool
+	locks          *emailLocks
+	// sendMiddleware wraps routes that send emails, e.g. rate limiting
+	sendMiddleware middleware.Middleware
 }
 
 type EmailService interface {
-	SendVerificationEmail(to, verificationLink string) error
-	SendConfirmationEmail(to string) error
+	SendVerificationEmail(to, verificationLink, acceptLanguage string) error
+	SendConfirmationEmail(to, acceptLanguage string) error
 }
 
 type HashService interface {
 	GetHash(email string) string
 }
 
+// Verifier is implemented by hash services issuing self-contained signed tokens.
+// When hash service is a Verifier, records are neither saved nor loaded from Storage
+type Verifier interface {
+	Verify(hash string) (map[string]string, error)
+}
+
 type Storage interface {
-	Save(email string, hash string) error
-	Load(hash string) (map[string]string, error)
-	Delete(hash string) error
+	Save(ctx context.Context, email string, hash string) error
+	Load(ctx context.Context, hash string) (map[string]string, error)
+	Delete(ctx context.Context, hash string) error
+	SaveEmailIndex(ctx context.Context, index storage.EmailIndex) error
+	LoadEmailIndex(ctx context.Context, email string) (storage.EmailIndex, error)
+}
+
+// Owners mints owner identity of verified email
+type Owners interface {
+	Issue(ctx context.Context, email string) (string, error)
+	SetSession(w http.ResponseWriter, key string)
+}
+
+// Registry remembers verified emails beyond lifetime of verification record
+type Registry interface {
+	Record(ctx context.Context, email, ip string) error
+}
+
+// Addresses normalizes recipient address and rejects undeliverable ones.
+// Mailbox is identity of address, see address.Checker
+type Addresses interface {
+	Check(ctx context.Context, email string) (string, error)
+	Mailbox(raw string) (string, error)
+}
+
+type LinkBuilder interface {
+	Build(elems ...string) string
 }
 
 type Validator interface {
 	Validate(str any) error
 }
 
+// Options tune verification routes
+type Options struct {
+	// ResendCooldown is minimal delay between emails of one pending verification
+	ResendCooldown time.Duration
+	// TrustProxy takes client IP recorded with verified email from X-Forwarded-For
+	TrustProxy bool
+	// ExposeLink returns verification link in send response, for local
+	// development without mailbox only. Link grants owner identity of email
+	ExposeLink bool
+	// SendMiddlewares wrap routes that send emails, e.g. rate limiting
+	SendMiddlewares []middleware.Middleware
+}
+
 type SendRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
+// SendResponse carries verification link only when [Options] ExposeLink
+// is set, elsewhere link must reach nobody but the mailbox owner
 type SendResponse struct {
 	Message string `json:"message"`
-	Link    string `json:"verification_link"`
+	Link    string `json:"verification_link,omitempty"`
 }
 
 func New(mux *http.ServeMux, logger l.Logger, emailService EmailService, hashService HashService,
-	storage Storage, validator Validator) error {
+	storage Storage, validator Validator, links LinkBuilder, owners Owners, addresses Addresses,
+	verified Registry, opts Options) error {
 	handler := &Handler{
-		Handler:      base.Handler{Logger: logger},
-		emailService: emailService,
-		hashService:  hashService,
-		storage:      storage,
-		validator:    validator,
+		Handler:        base.Handler{Logger: logger},
+		emailService:   emailService,
+		hashService:    hashService,
+		storage:        storage,
+		validator:      validator,
+		links:          links,
+		owners:         owners,
+		addresses:      addresses,
+		verified:       verified,
+		resendCooldown: opts.ResendCooldown,
+		trustProxy:     opts.TrustProxy,
+		exposeLink:     opts.ExposeLink,
+		locks:          newEmailLocks(),
+		sendMiddleware: middleware.Chain(opts.SendMiddlewares...),
 	}
 	if handler.validator == nil {
 		return errors.NewStructValidationError("validator required")
 	}
 
+	if verifier, ok := hashService.(Verifier); ok {
+		handler.verifier = verifier
+	} else if storage == nil {
+		return errors.NewStructValidationError("storage required for stored verification hashes")
+	}
+
 	if err := handler.validator.Validate(handler); err != nil {
 		return errors.Wrap("invalid handler", err)
 	}
@@ -76,91 +156,182 @@ func New(mux *http.ServeMux, logger l.Logger, emailService EmailService, hashSer
 }
 
 func (h *Handler) registerRoutes(router *http.ServeMux) {
-	router.HandleFunc("POST "+V1SEND, h.SendVerification)
+	send := h.sendMiddleware(http.HandlerFunc(h.SendVerification))
+
+	router.Handle("POST "+V1SEND, send)
 	router.HandleFunc("GET "+V1VERIFY, h.VerifyEmail)
 
-	router.HandleFunc("POST "+SEND, h.SendVerification)
+	router.Handle("POST "+SEND, send)
 	router.HandleFunc("GET "+VERIFY, h.VerifyEmail)
 
+	// resend and status find verification by email, which needs index in storage
+	if h.storage != nil {
+		resend := h.sendMiddleware(http.HandlerFunc(h.ResendVerification))
+
+		router.Handle("POST "+V1RESEND, resend)
+		router.HandleFunc("GET "+V1STATUS, h.VerificationStatus)
+
+		router.Handle("POST "+RESEND, resend)
+		router.HandleFunc("GET "+STATUS, h.VerificationStatus)
+	}
+
 	h.Logger.Debug("verification handler routes registered")
 }
 
 func (h *Handler) SendVerification(w http.ResponseWriter, r *http.Request) {
 	var req SendRequest
 	if err := h.ParseJSON(r, &req); err != nil {
-		h.Logger.Error(errors.Wrap("invalid request", err).Error())
+		h.Log(r).Error(errors.Wrap("invalid request", err).Error())
 		h.WriteError(w, errors.NewJsonParseError(err.Error()))
 		return
 	}
 
 	if err := h.validator.Validate(req); err != nil {
-		h.Logger.Error(errors.NewStructValidationError(err.Error()).Error())
+		h.Log(r).Error(errors.NewStructValidationError(err.Error()).Error())
 		h.WriteError(w, errors.NewStructValidationError(err.Error()))
 		return
 	}
 
-	hash := h.hashService.GetHash(req.Email)
-	verificationLink := fmt.Sprintf("http://localhost:8081/verify/%s", hash)
-
-	if err := h.emailService.SendVerificationEmail(req.Email, verificationLink); err != nil {
-		h.Logger.Error(errors.NewEmailSendingError(err.Error()).Error())
-		h.WriteError(w, errors.NewEmailSendingError(err.Error()))
+	email, err := h.addresses.Check(r.Context(), req.Email)
+	if err != nil {
+		h.Log(r).Warn("Email address rejected", "email", req.Email, "reason", err)
+		h.WriteError(w, errors.NewValidationError(err.Error()))
 		return
 	}
 
-	if err := h.storage.Save(req.Email, hash); err != nil {
-		h.Logger.Error(errors.NewStorageError(err.Error()).Error())
+	mailbox := h.mailbox(email)
+	unlock := h.locks.lock(mailbox)
+	defer unlock()
+
+	hash := h.hashService.GetHash(email)
+	verificationLink := h.links.Build("verify", hash)
+
+	if err := h.save(r.Context(), email, hash); err != nil {
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
 		h.WriteError(w, errors.NewStorageError(err.Error()))
 		return
 	}
 
-	response := SendResponse{
-		Message: "Verification email sent successfully",
-		Link:    verificationLink,
+	if err := h.emailService.SendVerificationEmail(email, verificationLink, r.Header.Get("Accept-Language")); err != nil {
+		h.Log(r).Error(errors.NewEmailSendingError(err.Error()).Error())
+		if delErr := h.delete(r.Context(), hash); delErr != nil {
+			h.Log(r).Warn("Failed to delete verification record", "hash", hash, "error", delErr)
+		}
+		h.WriteError(w, errors.NewEmailSendingError(err.Error()))
+		return
+	}
+
+	h.track(r.Context(), mailbox, hash)
+
+	response := SendResponse{Message: "Verification email queued for delivery"}
+	if h.exposeLink {
+		response.Link = verificationLink
 	}
 
 	h.WriteJSON(w, http.StatusOK, response)
-	h.Logger.Info("Verification email sent", "email", req.Email)
+	h.Log(r).Info("Verification email queued", "email", email)
 }
 
 func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	hash := r.PathValue("hash")
 	if hash == "" {
-		h.Logger.Error("Verification email hash required")
+		h.Log(r).Error("Verification email hash required")
 		h.WriteError(w, errors.NewValidationError("Hash parameter is required"))
 		return
 	}
 
-	credentials, err := h.storage.Load(hash)
+	credentials, err := h.load(r.Context(), hash)
 	if err != nil {
-		h.Logger.Error(errors.NewStorageError(err.Error()).Error())
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
 		h.WriteError(w, errors.NewNotFoundError("Invalid or expired verification link"))
 		return
 	}
 
-	receivedEmail := credentials["email"]
-	receivedHash := credentials["hash"]
+	receivedEmail := credentials[storage.KeyEmail]
+	receivedHash := credentials[storage.KeyHash]
 
 	if !validateRequest(hash, receivedHash) {
-		h.Logger.Warn("Invalid or expired verification link")
+		h.Log(r).Warn("Invalid or expired verification link")
 		h.WriteError(w, errors.NewValidationError("Invalid or expired verification link"))
+		return
+	}
+
+	if storage.Expired(credentials, time.Now().UTC()) {
+		h.Log(r).Warn("Verification link expired", "email", receivedEmail)
+		if err := h.delete(r.Context(), hash); err != nil {
+			h.Log(r).Warn("Failed to delete expired verification record", "hash", hash, "error", err)
+		}
+		h.WriteError(w, errors.NewVerificationExpiredError("Verification link has expired, request a new one"))
+		return
+	}
+
+	// record keeps address email was sent to, identity is its mailbox
+	mailbox := h.mailbox(receivedEmail)
+
+	// registry and owner key go first, verification record is deleted only
+	// after both are saved so failed attempt can be retried with the same link
+	if err := h.verified.Record(r.Context(), mailbox, middleware.ClientIP(r, h.trustProxy)); err != nil {
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError("Email could not be verified, try again"))
+		return
+	}
+
+	// verified email becomes owner identity, key is shown only once
+	apiKey, err := h.owners.Issue(r.Context(), mailbox)
+	if err != nil {
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError("API key could not be issued, try the same link again"))
+		return
 	}
 
-	if err := h.storage.Delete(hash); err != nil {
-		h.Logger.Warn("Failed to delete verification record", "hash", hash, "error", err)
+	if err := h.delete(r.Context(), hash); err != nil {
+		h.Log(r).Warn("Failed to delete verification record", "hash", hash, "error", err)
 	}
+	h.markVerified(r.Context(), mailbox, hash)
+	h.owners.SetSession(w, apiKey)
 
-	if err := h.emailService.SendConfirmationEmail(receivedEmail); err != nil {
-		h.Logger.Warn("Failed to send confirmation email", "email", receivedEmail, "error", err)
+	if err := h.emailService.SendConfirmationEmail(receivedEmail, r.Header.Get("Accept-Language")); err != nil {
+		h.Log(r).Warn("Failed to send confirmation email", "email", receivedEmail, "error", err)
 	}
 
 	response := map[string]string{
 		"message": "Email verified successfully",
 		"email":   receivedEmail,
+		"api_key": apiKey,
 	}
 
 	h.WriteJSON(w, http.StatusOK, response)
-	h.Logger.Info("Email verified successfully", "email", receivedEmail)
+	h.Log(r).Info("Email verified successfully", "email", receivedEmail)
+}
+
+// save persists stored hash, signed tokens carry everything and are not saved
+func (h *Handler) save(ctx context.Context, email, hash string) error {
+	if h.verifier != nil {
+		return nil
+	}
+	return h.storage.Save(ctx, email, hash)
+}
+
+func (h *Handler) load(ctx context.Context, hash string) (map[string]string, error) {
+	if h.verifier != nil {
+		return h.verifier.Verify(hash)
+	}
+	return h.storage.Load(ctx, hash)
+}
+
+func (h *Handler) delete(ctx context.Context, hash string) error {
+	if h.verifier != nil {
+		return nil
+	}
+	return h.storage.Delete(ctx, hash)
+}
+
+// mailbox returns identity of checked address, see [Addresses]
+func (h *Handler) mailbox(email string) string {
+	if mailbox, err := h.addresses.Mailbox(email); err == nil {
+		return mailbox
+	}
+	return email
 }
 
 func validateRequest(requestedHash string, storedHash string) bool {
@@ -0,0 +1,407 @@
+package links
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"link_shortener/internal/http-server/handlers/base"
+	"link_shortener/pkg/errors"
+	"link_shortener/pkg/storage"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	V1IMPORT = "/api/v1/links:import"
+	V1EXPORT = "/api/v1/links:export"
+)
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	// maxImportRows bounds rows of single import, report is kept in memory
+	maxImportRows = 10000
+	maxImportBody = 16 << 20
+	maxImportLine = 1 << 20
+	// exportFlushRows is how many rows are written before response is flushed
+	exportFlushRows = 100
+)
+
+// csvColumns are columns of exported CSV, import reads the ones it knows by header
+var csvColumns = []string{"domain", "code", "url", "short_url", "disabled",
+	"max_clicks", "clicks", "protected", "created_at", "expires_at"}
+
+// ImportRow is single link of import. Code is accepted as alias so
+// exported files can be imported back
+type ImportRow struct {
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias,omitempty"`
+	Code      string     `json:"code,omitempty"`
+	Domain    string     `json:"domain,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks int        `json:"max_clicks,omitempty"`
+	Password  string     `json:"password,omitempty"`
+}
+
+// ImportResult reports outcome of one row, Row is 1-based data row number
+type ImportResult struct {
+	Row      int             `json:"row"`
+	Code     string          `json:"code,omitempty"`
+	ShortURL string          `json:"short_url,omitempty"`
+	Error    *base.ErrorInfo `json:"error,omitempty"`
+}
+
+// ImportResponse reports every processed row. Truncated import hit
+// [maxImportRows], aborted one could not read body to the end and Error
+// tells why. Either way StoppedAt is first row that was not processed
+type ImportResponse struct {
+	Imported  int             `json:"imported"`
+	Failed    int             `json:"failed"`
+	Truncated bool            `json:"truncated"`
+	Aborted   bool            `json:"aborted"`
+	StoppedAt int             `json:"stopped_at,omitempty"`
+	Error     *base.ErrorInfo `json:"error,omitempty"`
+	Rows      []ImportResult  `json:"rows"`
+}
+
+// Import creates links of authenticated owner from CSV with header row or
+// NDJSON body, format comes from format query or Content-Type. Rows are
+// processed one by one and every row gets result in the report. Report is
+// returned even when import stops early, rows before are already saved
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	ownerEmail, ok := h.requireOwner(w, r)
+	if !ok {
+		return
+	}
+
+	h.extendDeadlines(w, r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatFromMediaType(r.Header.Get("Content-Type"))
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+
+	response := ImportResponse{Rows: []ImportResult{}}
+	handle := func(row int, item ImportRow, rowErr error) bool {
+		if len(response.Rows) >= maxImportRows {
+			response.Truncated = true
+			response.StoppedAt = row
+			return false
+		}
+
+		result := ImportResult{Row: row}
+		if rowErr == nil {
+			var link storage.Link
+			link, rowErr = h.create(r.Context(), ownerEmail, item.request())
+			if rowErr == nil {
+				result.Code = link.Code
+				result.ShortURL = h.links.BuildFor(link.Domain, link.Code)
+			}
+		}
+
+		if rowErr != nil {
+			result.Error = errorInfo(rowErr)
+			response.Failed++
+		} else {
+			response.Imported++
+		}
+		response.Rows = append(response.Rows, result)
+		return true
+	}
+
+	var err error
+	switch format {
+	case formatCSV:
+		err = readCSV(r.Body, handle)
+	case formatNDJSON:
+		err = readNDJSON(r.Body, handle)
+	default:
+		h.WriteError(w, errors.NewValidationError("Unknown import format, use format=csv|ndjson or matching Content-Type"))
+		return
+	}
+	if err != nil {
+		// nothing was saved, plain error is all client needs
+		if len(response.Rows) == 0 {
+			h.Log(r).Warn("Import rejected", "owner", ownerEmail, "error", err)
+			h.WriteError(w, errors.NewValidationError(fmt.Sprintf("Import rejected: %s", err)))
+			return
+		}
+
+		response.Aborted = true
+		response.StoppedAt = response.Rows[len(response.Rows)-1].Row + 1
+		response.Error = errorInfo(errors.NewValidationError(
+			fmt.Sprintf("Import aborted at row %d: %s", response.StoppedAt, err)))
+		h.Log(r).Warn("Import aborted", "owner", ownerEmail, "row", response.StoppedAt, "error", err)
+	}
+	if response.Truncated {
+		h.Log(r).Warn("Import truncated", "owner", ownerEmail, "row", response.StoppedAt)
+	}
+
+	h.WriteJSON(w, http.StatusOK, response)
+	h.Log(r).Info("Links imported", "owner", ownerEmail, "imported", response.Imported, "failed", response.Failed)
+}
+
+// Export streams links of authenticated owner as CSV or NDJSON, format
+// comes from format query or Accept header and defaults to NDJSON
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	ownerEmail, ok := h.requireOwner(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatNDJSON
+		if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			format = formatCSV
+		}
+	}
+
+	var write func(link LinkResponse) error
+	var finish func() error
+	switch format {
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		write = func(link LinkResponse) error {
+			return writer.Write(csvRecord(link))
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		// header row stays buffered until first flush
+		_ = writer.Write(csvColumns)
+	case formatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(link LinkResponse) error {
+			return encoder.Encode(link)
+		}
+		finish = func() error { return nil }
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		h.WriteError(w, errors.NewValidationError("Unknown export format, use format=csv|ndjson"))
+		return
+	}
+
+	h.extendDeadlines(w, r)
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	rows := 0
+	err := h.storage.EachLink(r.Context(), ownerEmail, func(link storage.Link) error {
+		if err := write(h.linkResponse(link)); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			if err := finish(); err != nil {
+				return err
+			}
+			_ = controller.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		// status is already sent, truncated body is all client can get
+		h.Log(r).Error(errors.Wrap("export failed", err).Error(), "owner", ownerEmail, "rows", rows)
+		return
+	}
+
+	h.Log(r).Info("Links exported", "owner", ownerEmail, "rows", rows)
+}
+
+// extendDeadlines replaces server read and write deadlines of connection
+// with bulkTimeout, large uploads and long exports outlast regular requests
+func (h *Handler) extendDeadlines(w http.ResponseWriter, r *http.Request) {
+	if h.bulkTimeout <= 0 {
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	deadline := time.Now().Add(h.bulkTimeout)
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		h.Log(r).Warn("Failed to extend read deadline", "error", err)
+	}
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		h.Log(r).Warn("Failed to extend write deadline", "error", err)
+	}
+}
+
+func (row ImportRow) request() CreateRequest {
+	alias := row.Alias
+	if alias == "" {
+		alias = row.Code
+	}
+	return CreateRequest{
+		URL:       row.URL,
+		Alias:     alias,
+		Domain:    row.Domain,
+		ExpiresAt: row.ExpiresAt,
+		MaxClicks: row.MaxClicks,
+		Password:  row.Password,
+	}
+}
+
+// readCSV passes data rows to handle, header row names columns. Malformed
+// rows are reported and skipped, reading stops when handle returns false
+func readCSV(body io.Reader, handle func(row int, item ImportRow, err error) bool) error {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return fmt.Errorf("header has no url column")
+	}
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if err != nil && !stdErrors.As(err, &parseErr) {
+			return err
+		}
+
+		var item ImportRow
+		if err == nil {
+			item, err = csvRow(columns, record)
+		}
+		if err != nil {
+			err = errors.NewValidationError(err.Error())
+		}
+		if !handle(row, item, err) {
+			return nil
+		}
+	}
+}
+
+func csvRow(columns map[string]int, record []string) (ImportRow, error) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	item := ImportRow{
+		URL:      get("url"),
+		Alias:    get("alias"),
+		Code:     get("code"),
+		Domain:   get("domain"),
+		Password: get("password"),
+	}
+
+	if raw := get("expires_at"); raw != "" {
+		expiresAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return ImportRow{}, fmt.Errorf("invalid expires_at: %s", raw)
+		}
+		item.ExpiresAt = &expiresAt
+	}
+	if raw := get("max_clicks"); raw != "" {
+		maxClicks, err := strconv.Atoi(raw)
+		if err != nil {
+			return ImportRow{}, fmt.Errorf("invalid max_clicks: %s", raw)
+		}
+		item.MaxClicks = maxClicks
+	}
+
+	return item, nil
+}
+
+// readNDJSON passes one JSON object per line to handle, blank lines are
+// skipped and malformed ones reported
+func readNDJSON(body io.Reader, handle func(row int, item ImportRow, err error) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			row--
+			continue
+		}
+
+		var item ImportRow
+		var err error
+		if err = json.Unmarshal([]byte(line), &item); err != nil {
+			err = errors.NewJsonParseError(err.Error())
+		}
+		if !handle(row, item, err) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// csvRecord renders link in [csvColumns] order
+func csvRecord(link LinkResponse) []string {
+	expiresAt := ""
+	if !link.ExpiresAt.IsZero() {
+		expiresAt = link.ExpiresAt.Format(time.RFC3339)
+	}
+	return []string{
+		link.Domain,
+		link.Code,
+		link.URL,
+		link.ShortURL,
+		strconv.FormatBool(link.Disabled),
+		strconv.Itoa(link.MaxClicks),
+		strconv.Itoa(link.Clicks),
+		strconv.FormatBool(link.Protected),
+		link.CreatedAt.Format(time.RFC3339),
+		expiresAt,
+	}
+}
+
+func formatFromMediaType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return formatNDJSON
+	default:
+		return ""
+	}
+}
+
+// errorInfo converts row error to the shape of API error responses
+func errorInfo(err error) *base.ErrorInfo {
+	if appErr, ok := errors.AsAppError(err); ok {
+		return &base.ErrorInfo{
+			Code:    appErr.Code,
+			Message: appErr.Message,
+			Details: appErr.Details,
+		}
+	}
+	return &base.ErrorInfo{
+		Code:    errors.ErrInternal.Code,
+		Message: errors.ErrInternal.Message,
+	}
+}
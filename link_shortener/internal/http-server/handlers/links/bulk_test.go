@@ -0,0 +1,274 @@
+package links
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"link_shortener/internal/http-server/handlers/base"
+	l "link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testOwner = "owner@example.com"
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any)   {}
+func (nopLogger) Info(string, ...any)    {}
+func (nopLogger) Warn(string, ...any)    {}
+func (nopLogger) Error(string, ...any)   {}
+func (n nopLogger) With(...any) l.Logger { return n }
+
+// memStorage keeps links in memory, EachLink pauses before every link
+// to simulate slow export
+type memStorage struct {
+	Storage
+	mu    sync.Mutex
+	links []storage.Link
+	pause time.Duration
+}
+
+func (s *memStorage) SaveLink(_ context.Context, link storage.Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links = append(s.links, link)
+	return nil
+}
+
+func (s *memStorage) EachLink(_ context.Context, owner string, visit func(link storage.Link) error) error {
+	s.mu.Lock()
+	links := append([]storage.Link(nil), s.links...)
+	s.mu.Unlock()
+
+	for _, link := range links {
+		time.Sleep(s.pause)
+		if link.Owner != owner {
+			continue
+		}
+		if err := visit(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type counter struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (c *counter) Generate() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	return fmt.Sprintf("c%d", c.next), nil
+}
+
+type acceptAll struct{}
+
+func (acceptAll) Validate(any) error                         { return nil }
+func (acceptAll) Check(context.Context, string) error        { return nil }
+func (acceptAll) Authenticate(*http.Request) (string, error) { return testOwner, nil }
+
+type hostLinks struct{}
+
+func (hostLinks) BuildFor(host string, elems ...string) string {
+	return "http://short.test/" + strings.Join(elems, "/")
+}
+
+func newBulkHandler(store *memStorage, bulkTimeout time.Duration) *Handler {
+	return &Handler{
+		Handler:     base.Handler{Logger: nopLogger{}},
+		storage:     store,
+		generator:   &counter{},
+		validator:   acceptAll{},
+		links:       hostLinks{},
+		owners:      acceptAll{},
+		policy:      acceptAll{},
+		bulkTimeout: bulkTimeout,
+	}
+}
+
+// serveBulk starts server whose read and write timeouts are timeout
+func serveBulk(t *testing.T, h *Handler, timeout time.Duration) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+V1IMPORT, h.Import)
+	mux.HandleFunc("GET "+V1EXPORT, h.Export)
+
+	server := httptest.NewUnstartedServer(mux)
+	server.Config.ReadTimeout = timeout
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// slowBody writes lines one by one, pausing before each of them
+func slowBody(lines []string, pause time.Duration) io.Reader {
+	reader, writer := io.Pipe()
+	go func() {
+		for _, line := range lines {
+			time.Sleep(pause)
+			if _, err := io.WriteString(writer, line+"\n"); err != nil {
+				return
+			}
+		}
+		_ = writer.Close()
+	}()
+	return reader
+}
+
+func importRequest(t *testing.T, url string, body io.Reader) (ImportResponse, error) {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodPost, url+V1IMPORT+"?format=ndjson", body)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return ImportResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ImportResponse{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return decodeReport(resp.Body)
+}
+
+// decodeReport reads import report out of response envelope
+func decodeReport(body io.Reader) (ImportResponse, error) {
+	var report ImportResponse
+	err := json.NewDecoder(body).Decode(&base.Response{Data: &report})
+	return report, err
+}
+
+func TestImportOutlastsServerTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	lines := []string{
+		`{"url":"https://example.com/1"}`,
+		`{"url":"https://example.com/2"}`,
+		`{"url":"https://example.com/3"}`,
+	}
+
+	t.Run("bulk timeout", func(t *testing.T) {
+		server := serveBulk(t, newBulkHandler(&memStorage{}, 5*time.Second), timeout)
+
+		report, err := importRequest(t, server.URL, slowBody(lines, timeout))
+		if err != nil {
+			t.Fatalf("import: %v", err)
+		}
+		if report.Imported != len(lines) || report.Aborted || report.Truncated {
+			t.Errorf("got %+v, want %d rows imported", report, len(lines))
+		}
+	})
+
+	// guards the case above, slow body must really outlast server timeout
+	t.Run("server timeout", func(t *testing.T) {
+		server := serveBulk(t, newBulkHandler(&memStorage{}, 0), timeout)
+
+		report, err := importRequest(t, server.URL, slowBody(lines, timeout))
+		if err == nil && !report.Aborted {
+			t.Errorf("got %+v, want import cut by server timeout", report)
+		}
+	})
+}
+
+func TestExportOutlastsServerTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	store := &memStorage{pause: timeout / 2}
+	for i := range 5 {
+		store.links = append(store.links, storage.Link{
+			Code: fmt.Sprintf("c%d", i), URL: "https://example.com", Owner: testOwner,
+		})
+	}
+	server := serveBulk(t, newBulkHandler(store, 5*time.Second), timeout)
+
+	resp, err := http.Get(server.URL + V1EXPORT)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	defer resp.Body.Close()
+
+	rows := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		rows++
+	}
+	if err = scanner.Err(); err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	if rows != len(store.links) {
+		t.Errorf("got %d rows, want %d", rows, len(store.links))
+	}
+}
+
+func TestImportReportsEarlyStop(t *testing.T) {
+	row := `{"url":"https://example.com"}`
+
+	tests := []struct {
+		name      string
+		body      string
+		imported  int
+		stoppedAt int
+		truncated bool
+		aborted   bool
+	}{
+		{
+			name:     "complete",
+			body:     strings.Repeat(row+"\n", 3),
+			imported: 3,
+		},
+		{
+			name:      "too many rows",
+			body:      strings.Repeat(row+"\n", maxImportRows+5),
+			imported:  maxImportRows,
+			stoppedAt: maxImportRows + 1,
+			truncated: true,
+		},
+		{
+			name:      "line too long",
+			body:      strings.Repeat(row+"\n", 2) + strings.Repeat("x", maxImportLine+1) + "\n" + row + "\n",
+			imported:  2,
+			stoppedAt: 3,
+			aborted:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newBulkHandler(&memStorage{}, 0)
+			request := httptest.NewRequest(http.MethodPost, V1IMPORT+"?format=ndjson", strings.NewReader(tt.body))
+			recorder := httptest.NewRecorder()
+
+			h.Import(recorder, request)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status %d, want 200: %s", recorder.Code, recorder.Body)
+			}
+			report, err := decodeReport(recorder.Body)
+			if err != nil {
+				t.Fatalf("decode report: %v", err)
+			}
+			if report.Imported != tt.imported || len(report.Rows) != tt.imported {
+				t.Errorf("imported %d with %d rows, want %d", report.Imported, len(report.Rows), tt.imported)
+			}
+			if report.Truncated != tt.truncated || report.Aborted != tt.aborted || report.StoppedAt != tt.stoppedAt {
+				t.Errorf("got truncated=%t aborted=%t stopped_at=%d, want %t %t %d",
+					report.Truncated, report.Aborted, report.StoppedAt, tt.truncated, tt.aborted, tt.stoppedAt)
+			}
+			if tt.aborted && report.Error == nil {
+				t.Error("aborted report has no error")
+			}
+		})
+	}
+}
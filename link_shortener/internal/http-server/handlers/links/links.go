@@ -0,0 +1,556 @@
+package links
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"link_shortener/internal/http-server/handlers/base"
+	"link_shortener/internal/services/analytics"
+	"link_shortener/internal/services/owner"
+	"link_shortener/internal/services/urlpolicy"
+	"link_shortener/pkg/errors"
+	l "link_shortener/pkg/logger"
+	"link_shortener/pkg/middleware"
+	"link_shortener/pkg/security"
+	"link_shortener/pkg/storage"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	V1LINKS  = "/api/v1/links"
+	V1LINK   = "/api/v1/links/{code}"
+	V1STATS  = "/api/v1/links/{code}/stats"
+	V1QR     = "/api/v1/links/{code}/qr"
+	REDIRECT = "/{code}"
+)
+
+// defaultStatsPeriod is reported when stats request has no "from"
+const defaultStatsPeriod = 30 * 24 * time.Hour
+
+var statsBuckets = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+// maxGenerateAttempts limits retries when generated code is already taken
+const maxGenerateAttempts = 5
+
+type Handler struct {
+	base.Handler `validate:"required"`
+	storage      Storage       `validate:"required"`
+	generator    CodeGenerator `validate:"required"`
+	validator    Validator     `validate:"required"`
+	links        LinkBuilder   `validate:"required"`
+	analytics    Analytics     `validate:"required"`
+	owners       Owners        `validate:"required"`
+	policy       URLPolicy     `validate:"required"`
+	reserved     ReservedChecker
+	// domains are configured vanity hosts
+	domains      map[string]struct{}
+	anonymousTTL time.Duration
+	// bulkTimeout is read and write deadline of import and export
+	bulkTimeout time.Duration
+	// unlockMiddleware wraps password submission route, e.g. rate limiting
+	unlockMiddleware middleware.Middleware
+}
+
+// Options tune link creation, see [config.Links]
+type Options struct {
+	Domains      []string
+	AnonymousTTL time.Duration
+	// BulkTimeout replaces server deadlines of import and export, zero
+	// keeps server ones
+	BulkTimeout time.Duration
+	// UnlockMiddlewares wrap password submission of protected links
+	UnlockMiddlewares []middleware.Middleware
+}
+
+type Storage interface {
+	SaveLink(ctx context.Context, link storage.Link) error
+	LoadLink(ctx context.Context, domain, code string) (storage.Link, error)
+	UpdateLink(ctx context.Context, link storage.Link) error
+	CountRedirect(ctx context.Context, domain, code string) error
+	DeleteLink(ctx context.Context, domain, code string) error
+	ListLinks(ctx context.Context, owner string) ([]storage.Link, error)
+	EachLink(ctx context.Context, owner string, visit func(link storage.Link) error) error
+}
+
+// Owners resolves verified owner of request, anonymous requests get empty owner
+type Owners interface {
+	Authenticate(r *http.Request) (string, error)
+}
+
+type CodeGenerator interface {
+	Generate() (string, error)
+}
+
+type LinkBuilder interface {
+	BuildFor(host string, elems ...string) string
+}
+
+type Analytics interface {
+	Track(domain, code string, r *http.Request)
+	Clicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error)
+}
+
+type Validator interface {
+	Validate(str any) error
+}
+
+// URLPolicy screens target URLs, rejections are [urlpolicy.Violation]
+type URLPolicy interface {
+	Check(ctx context.Context, raw string) error
+}
+
+// ReservedChecker is implemented by validators knowing words that shadow
+// service routes, generated codes hitting one are discarded
+type ReservedChecker interface {
+	IsReserved(word string) bool
+}
+
+// CreateRequest optionally carries custom alias used instead of generated
+// code, vanity domain the link is scoped to and link policies
+type CreateRequest struct {
+	URL       string     `json:"url" validate:"required,url"`
+	Alias     string     `json:"alias,omitempty" validate:"omitempty,min=3,max=64,alias"`
+	Domain    string     `json:"domain,omitempty" validate:"omitempty,hostname"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxClicks int        `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	Password  string     `json:"password,omitempty" validate:"omitempty,min=4,max=72"`
+}
+
+type CreateResponse struct {
+	Domain    string    `json:"domain,omitempty"`
+	Code      string    `json:"code"`
+	ShortURL  string    `json:"short_url"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+func New(mux *http.ServeMux, logger l.Logger, storage Storage, generator CodeGenerator,
+	validator Validator, links LinkBuilder, analytics Analytics, owners Owners, policy URLPolicy,
+	opts Options) error {
+	handler := &Handler{
+		Handler:      base.Handler{Logger: logger},
+		storage:      storage,
+		generator:    generator,
+		validator:    validator,
+		links:        links,
+		analytics:    analytics,
+		owners:       owners,
+		policy:       policy,
+		domains:      make(map[string]struct{}, len(opts.Domains)),
+		anonymousTTL: opts.AnonymousTTL,
+		bulkTimeout:  opts.BulkTimeout,
+
+		unlockMiddleware: middleware.Chain(opts.UnlockMiddlewares...),
+	}
+	if handler.validator == nil {
+		return errors.NewStructValidationError("validator required")
+	}
+
+	if reserved, ok := validator.(ReservedChecker); ok {
+		handler.reserved = reserved
+	}
+
+	for _, domain := range opts.Domains {
+		if domain = normalizeHost(domain); domain != "" {
+			handler.domains[domain] = struct{}{}
+		}
+	}
+
+	if err := handler.validator.Validate(handler); err != nil {
+		return errors.Wrap("invalid handler", err)
+	}
+
+	handler.registerRoutes(mux)
+
+	handler.Logger.Debug("links handler created and routes registered")
+
+	return nil
+}
+
+func (h *Handler) registerRoutes(router *http.ServeMux) {
+	router.HandleFunc("POST "+V1LINKS, h.CreateLink)
+	router.HandleFunc("GET "+V1LINKS, h.ListLinks)
+	router.HandleFunc("POST "+V1IMPORT, h.Import)
+	router.HandleFunc("GET "+V1EXPORT, h.Export)
+	router.HandleFunc("PATCH "+V1LINK, h.UpdateLink)
+	router.HandleFunc("DELETE "+V1LINK, h.DeleteLink)
+	router.HandleFunc("GET "+V1STATS, h.Stats)
+	router.HandleFunc("GET "+V1QR, h.QR)
+	router.HandleFunc("GET "+REDIRECT, h.Redirect)
+	router.Handle("POST "+REDIRECT, h.unlockMiddleware(http.HandlerFunc(h.Unlock)))
+
+	h.Logger.Debug("links handler routes registered")
+}
+
+func (h *Handler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := h.ParseJSON(r, &req); err != nil {
+		h.Log(r).Error(errors.Wrap("invalid request", err).Error())
+		h.WriteError(w, errors.NewJsonParseError(err.Error()))
+		return
+	}
+
+	ownerEmail, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := h.create(r.Context(), ownerEmail, req)
+	if err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	response := CreateResponse{
+		Domain:    link.Domain,
+		Code:      link.Code,
+		ShortURL:  h.links.BuildFor(link.Domain, link.Code),
+		URL:       link.URL,
+		ExpiresAt: link.ExpiresAt,
+	}
+
+	h.WriteJSON(w, http.StatusCreated, response)
+}
+
+// create validates req, applies URL policy and owner restrictions and saves
+// link, failures are returned as AppError ready to be written to client
+func (h *Handler) create(ctx context.Context, ownerEmail string, req CreateRequest) (storage.Link, error) {
+	log := l.FromContext(ctx, h.Logger)
+	if err := h.validator.Validate(req); err != nil {
+		log.Error(errors.NewValidationError(err.Error()).Error())
+		return storage.Link{}, errors.NewValidationError(err.Error())
+	}
+
+	domain := normalizeHost(req.Domain)
+	if domain != "" && !h.isDomain(domain) {
+		return storage.Link{}, errors.NewValidationError(fmt.Sprintf("Domain %s is not configured", req.Domain))
+	}
+
+	// anonymous callers only get short-lived links with generated codes
+	if ownerEmail == "" && (req.Alias != "" || domain != "") {
+		return storage.Link{}, errors.NewUnauthorizedError("Verify your email to use aliases and domains")
+	}
+
+	if err := h.checkURL(ctx, req.URL); err != nil {
+		return storage.Link{}, err
+	}
+
+	now := time.Now().UTC()
+	link := storage.Link{
+		Domain:    domain,
+		URL:       req.URL,
+		Owner:     ownerEmail,
+		MaxClicks: req.MaxClicks,
+	}
+
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(now) {
+			return storage.Link{}, errors.NewValidationError("expires_at must be in the future")
+		}
+		link.ExpiresAt = req.ExpiresAt.UTC()
+	}
+
+	if limit := now.Add(h.anonymousTTL); ownerEmail == "" && h.anonymousTTL > 0 &&
+		(link.ExpiresAt.IsZero() || link.ExpiresAt.After(limit)) {
+		link.ExpiresAt = limit
+	}
+
+	var err error
+	if req.Password != "" {
+		if len(req.Password) > security.MaxPasswordBytes {
+			return storage.Link{}, passwordTooLong()
+		}
+		if link.PasswordHash, err = security.HashPassword(req.Password); err != nil {
+			log.Error(errors.Wrap("failed to hash link password", err).Error())
+			return storage.Link{}, errors.NewStorageError(err.Error())
+		}
+	}
+
+	if req.Alias != "" {
+		link, err = h.saveAlias(ctx, link, req.Alias)
+	} else {
+		link, err = h.saveWithUniqueCode(ctx, link)
+	}
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrAlreadyExists) {
+			log.Warn("Alias already taken", "domain", domain, "alias", req.Alias)
+			return storage.Link{}, errors.NewConflictError(fmt.Sprintf("Alias %s is already taken", req.Alias))
+		}
+		log.Error(errors.NewStorageError(err.Error()).Error())
+		return storage.Link{}, errors.NewStorageError(err.Error())
+	}
+
+	log.Info("Short link created", "domain", link.Domain, "code", link.Code,
+		"url", link.URL, "owner", link.Owner)
+
+	return link, nil
+}
+
+func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.WriteError(w, errors.NewValidationError("Code parameter is required"))
+		return
+	}
+
+	link, err := h.resolve(r.Context(), normalizeHost(r.Host), code)
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	if !h.available(w, link) {
+		return
+	}
+
+	if link.PasswordHash != "" {
+		h.renderPrompt(w, r, link, http.StatusOK, "")
+		return
+	}
+
+	h.follow(w, r, link)
+}
+
+// available answers request for link which must not be followed: disabled
+// links look missing, expired and exhausted ones are gone
+func (h *Handler) available(w http.ResponseWriter, link storage.Link) bool {
+	switch {
+	case link.Disabled:
+		h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+		return false
+	case link.Expired(time.Now().UTC()):
+		h.WriteError(w, errors.NewLinkGoneError("Short link has expired"))
+		return false
+	case link.Exhausted():
+		h.WriteError(w, errors.NewLinkGoneError("Short link reached its click limit"))
+		return false
+	}
+	return true
+}
+
+// follow counts redirect against click cap and redirects to target
+func (h *Handler) follow(w http.ResponseWriter, r *http.Request, link storage.Link) {
+	if link.MaxClicks > 0 {
+		if err := h.storage.CountRedirect(r.Context(), link.Domain, link.Code); err != nil {
+			if stdErrors.Is(err, storage.ErrLimitReached) {
+				h.WriteError(w, errors.NewLinkGoneError("Short link reached its click limit"))
+				return
+			}
+			if stdErrors.Is(err, storage.ErrNotFound) {
+				h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+				return
+			}
+			h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+			h.WriteError(w, errors.NewStorageError(err.Error()))
+			return
+		}
+	}
+
+	h.analytics.Track(link.Domain, link.Code, r)
+
+	http.Redirect(w, r, link.URL, http.StatusFound)
+	h.Log(r).Debug("Short link redirected", "domain", link.Domain, "code", link.Code)
+}
+
+// Stats reports clicks of link, query accepts bucket=hour|day,
+// RFC3339 from/to bounds and domain of scoped links, defaults to
+// daily series of last 30 days. Stats of owned links are private,
+// other callers are told the link does not exist
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	domain := normalizeHost(r.URL.Query().Get("domain"))
+
+	link, err := h.storage.LoadLink(r.Context(), domain, code)
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	if link.Owner != "" {
+		ownerEmail, ok := h.authenticate(w, r)
+		if !ok {
+			return
+		}
+		if ownerEmail != link.Owner {
+			h.Log(r).Warn("Short link stats access denied", "code", link.Code, "caller", ownerEmail)
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+	}
+
+	bucketName, bucket, from, to, err := parseStatsQuery(r)
+	if err != nil {
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	clicks, err := h.analytics.Clicks(r.Context(), domain, code, from, to)
+	if err != nil {
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, analytics.Aggregate(code, clicks, from, to, bucket, bucketName))
+}
+
+func parseStatsQuery(r *http.Request) (string, time.Duration, time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	bucketName := query.Get("bucket")
+	if bucketName == "" {
+		bucketName = "day"
+	}
+	bucket, ok := statsBuckets[bucketName]
+	if !ok {
+		return "", 0, time.Time{}, time.Time{}, fmt.Errorf("unknown bucket %q, use hour or day", bucketName)
+	}
+
+	to := time.Now().UTC()
+	if raw := query.Get("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return "", 0, time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = parsed.UTC()
+	}
+
+	from := to.Add(-defaultStatsPeriod)
+	if raw := query.Get("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return "", 0, time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = parsed.UTC()
+	}
+	from = from.Truncate(bucket)
+
+	if !from.Before(to) {
+		return "", 0, time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from)/bucket >= analytics.MaxBuckets {
+		return "", 0, time.Time{}, time.Time{}, fmt.Errorf("period too long, at most %d buckets allowed", analytics.MaxBuckets)
+	}
+
+	return bucketName, bucket, from, to, nil
+}
+
+// resolve looks link up in scope of vanity host first, links of default
+// domain are served on every host
+func (h *Handler) resolve(ctx context.Context, host, code string) (storage.Link, error) {
+	if h.isDomain(host) {
+		link, err := h.storage.LoadLink(ctx, host, code)
+		if !stdErrors.Is(err, storage.ErrNotFound) {
+			return link, err
+		}
+	}
+	return h.storage.LoadLink(ctx, "", code)
+}
+
+func (h *Handler) isDomain(host string) bool {
+	_, ok := h.domains[host]
+	return ok
+}
+
+// checkURL runs target URL through policy pipeline, rejection is
+// returned as URL_REJECTED AppError
+func (h *Handler) checkURL(ctx context.Context, target string) error {
+	err := h.policy.Check(ctx, target)
+	if err == nil {
+		return nil
+	}
+
+	if violation, ok := urlpolicy.AsViolation(err); ok {
+		l.FromContext(ctx, h.Logger).Warn("Target URL rejected", "url", target, "rule", violation.Rule, "reason", violation.Reason)
+		return errors.NewURLRejectedError(violation.Error())
+	}
+
+	l.FromContext(ctx, h.Logger).Error(errors.Wrap("url policy check failed", err).Error())
+	return errors.NewURLRejectedError("Target URL could not be checked")
+}
+
+// authenticate resolves owner of request, invalid key is answered with
+// 401 and reported as not ok, missing key yields anonymous owner
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ownerEmail, err := h.owners.Authenticate(r)
+	if err != nil {
+		if stdErrors.Is(err, owner.ErrUnauthenticated) {
+			h.WriteError(w, errors.NewUnauthorizedError("Invalid API key"))
+			return "", false
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return "", false
+	}
+	return ownerEmail, true
+}
+
+func (h *Handler) saveAlias(ctx context.Context, link storage.Link, alias string) (storage.Link, error) {
+	link.Code = alias
+	link.CreatedAt = time.Now().UTC()
+	if err := h.storage.SaveLink(ctx, link); err != nil {
+		return storage.Link{}, err
+	}
+	return link, nil
+}
+
+// passwordTooLong rejects password bcrypt can not hash, validator max
+// counts characters while the limit is in bytes
+func passwordTooLong() error {
+	return errors.NewValidationError(fmt.Sprintf("password must be at most %d bytes long", security.MaxPasswordBytes))
+}
+
+// saveWithUniqueCode generates codes until storage accepts one that is not taken yet
+func (h *Handler) saveWithUniqueCode(ctx context.Context, link storage.Link) (storage.Link, error) {
+	for range maxGenerateAttempts {
+		code, err := h.generator.Generate()
+		if err != nil {
+			return storage.Link{}, errors.Wrap("failed to generate code", err)
+		}
+
+		if h.reserved != nil && h.reserved.IsReserved(code) {
+			l.FromContext(ctx, h.Logger).Warn("Generated code is reserved, retrying", "code", code)
+			continue
+		}
+
+		link.Code = code
+		link.CreatedAt = time.Now().UTC()
+
+		err = h.storage.SaveLink(ctx, link)
+		if err == nil {
+			return link, nil
+		}
+		if !stdErrors.Is(err, storage.ErrAlreadyExists) {
+			return storage.Link{}, err
+		}
+		l.FromContext(ctx, h.Logger).Warn("Generated code collision, retrying", "code", code)
+	}
+
+	return storage.Link{}, fmt.Errorf("no free code after %d attempts", maxGenerateAttempts)
+}
+
+// normalizeHost lowercases host and strips port, so Host header and
+// configured domains compare equal
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
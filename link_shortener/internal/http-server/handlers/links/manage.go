@@ -0,0 +1,229 @@
+package links
+
+import (
+	stdErrors "errors"
+	"link_shortener/pkg/errors"
+	"link_shortener/pkg/security"
+	"link_shortener/pkg/storage"
+	"net/http"
+	"time"
+)
+
+// LinkResponse describes link to its owner
+type LinkResponse struct {
+	Domain    string    `json:"domain,omitempty"`
+	Code      string    `json:"code"`
+	ShortURL  string    `json:"short_url"`
+	URL       string    `json:"url"`
+	Disabled  bool      `json:"disabled"`
+	MaxClicks int       `json:"max_clicks,omitempty"`
+	Clicks    int       `json:"clicks"`
+	Protected bool      `json:"protected"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+type ListResponse struct {
+	Links []LinkResponse `json:"links"`
+}
+
+// UpdateRequest changes only fields present in request body. Empty
+// expires_at and password and zero max_clicks remove the policy
+type UpdateRequest struct {
+	URL       *string `json:"url,omitempty" validate:"omitempty,url"`
+	Disabled  *bool   `json:"disabled,omitempty"`
+	ExpiresAt *string `json:"expires_at,omitempty"`
+	MaxClicks *int    `json:"max_clicks,omitempty" validate:"omitempty,min=0"`
+	Password  *string `json:"password,omitempty" validate:"omitempty,max=72"`
+}
+
+// ListLinks returns links of authenticated owner
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	ownerEmail, ok := h.requireOwner(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := h.storage.ListLinks(r.Context(), ownerEmail)
+	if err != nil {
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	response := ListResponse{Links: make([]LinkResponse, 0, len(links))}
+	for _, link := range links {
+		response.Links = append(response.Links, h.linkResponse(link))
+	}
+
+	h.WriteJSON(w, http.StatusOK, response)
+}
+
+// UpdateLink changes target, disabled state and policies of owned link,
+// query accepts domain of scoped links
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.ownedLink(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRequest
+	if err := h.ParseJSON(r, &req); err != nil {
+		h.Log(r).Error(errors.Wrap("invalid request", err).Error())
+		h.WriteError(w, errors.NewJsonParseError(err.Error()))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	if req.URL != nil {
+		if err := h.checkURL(r.Context(), *req.URL); err != nil {
+			h.WriteError(w, err)
+			return
+		}
+	}
+
+	if err := applyUpdate(&link, req); err != nil {
+		h.WriteError(w, err)
+		return
+	}
+
+	if err := h.storage.UpdateLink(r.Context(), link); err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, h.linkResponse(link))
+	h.Log(r).Info("Short link updated", "domain", link.Domain, "code", link.Code, "owner", link.Owner)
+}
+
+// DeleteLink removes owned link, query accepts domain of scoped links
+func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := h.ownedLink(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteLink(r.Context(), link.Domain, link.Code); err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	h.WriteJSON(w, http.StatusOK, map[string]string{"message": "Short link deleted"})
+	h.Log(r).Info("Short link deleted", "domain", link.Domain, "code", link.Code, "owner", link.Owner)
+}
+
+// requireOwner is [Handler.authenticate] rejecting anonymous requests
+func (h *Handler) requireOwner(w http.ResponseWriter, r *http.Request) (string, bool) {
+	ownerEmail, ok := h.authenticate(w, r)
+	if !ok {
+		return "", false
+	}
+	if ownerEmail == "" {
+		h.WriteError(w, errors.NewUnauthorizedError("API key of verified email required"))
+		return "", false
+	}
+	return ownerEmail, true
+}
+
+// ownedLink loads link addressed by request and checks it belongs to caller
+func (h *Handler) ownedLink(w http.ResponseWriter, r *http.Request) (storage.Link, bool) {
+	ownerEmail, ok := h.requireOwner(w, r)
+	if !ok {
+		return storage.Link{}, false
+	}
+
+	link, err := h.storage.LoadLink(r.Context(), normalizeHost(r.URL.Query().Get("domain")), r.PathValue("code"))
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return storage.Link{}, false
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return storage.Link{}, false
+	}
+
+	if link.Owner == "" || link.Owner != ownerEmail {
+		h.Log(r).Warn("Short link access denied", "code", link.Code, "caller", ownerEmail)
+		h.WriteError(w, errors.NewForbiddenError("Short link belongs to another owner"))
+		return storage.Link{}, false
+	}
+
+	return link, true
+}
+
+// applyUpdate copies fields present in req to link
+func applyUpdate(link *storage.Link, req UpdateRequest) error {
+	if req.URL != nil {
+		link.URL = *req.URL
+	}
+	if req.Disabled != nil {
+		link.Disabled = *req.Disabled
+	}
+	if req.MaxClicks != nil {
+		link.MaxClicks = *req.MaxClicks
+	}
+
+	if req.ExpiresAt != nil {
+		if *req.ExpiresAt == "" {
+			link.ExpiresAt = time.Time{}
+		} else {
+			expiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+			if err != nil {
+				return errors.NewValidationError("expires_at must be RFC3339 time")
+			}
+			if !expiresAt.After(time.Now()) {
+				return errors.NewValidationError("expires_at must be in the future")
+			}
+			link.ExpiresAt = expiresAt.UTC()
+		}
+	}
+
+	if req.Password != nil {
+		switch password := *req.Password; {
+		case password == "":
+			link.PasswordHash = ""
+		case len(password) < 4:
+			return errors.NewValidationError("password must be at least 4 characters long")
+		case len(password) > security.MaxPasswordBytes:
+			return passwordTooLong()
+		default:
+			hash, err := security.HashPassword(password)
+			if err != nil {
+				return errors.NewStorageError(err.Error())
+			}
+			link.PasswordHash = hash
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) linkResponse(link storage.Link) LinkResponse {
+	return LinkResponse{
+		Domain:    link.Domain,
+		Code:      link.Code,
+		ShortURL:  h.links.BuildFor(link.Domain, link.Code),
+		URL:       link.URL,
+		Disabled:  link.Disabled,
+		MaxClicks: link.MaxClicks,
+		Clicks:    link.Clicks,
+		Protected: link.PasswordHash != "",
+		CreatedAt: link.CreatedAt,
+		ExpiresAt: link.ExpiresAt,
+	}
+}
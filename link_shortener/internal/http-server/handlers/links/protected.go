@@ -0,0 +1,85 @@
+package links
+
+import (
+	stdErrors "errors"
+	"html/template"
+	"link_shortener/pkg/errors"
+	"link_shortener/pkg/security"
+	"link_shortener/pkg/storage"
+	"net/http"
+)
+
+// maxPasswordForm bounds body of password form
+const maxPasswordForm = 4 << 10
+
+var promptTemplate = template.Must(template.New("prompt").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+<style>
+body{font-family:sans-serif;display:flex;justify-content:center;margin-top:15vh}
+form{display:flex;flex-direction:column;gap:.75rem;width:18rem}
+.error{color:#b00020}
+</style>
+</head>
+<body>
+<form method="post">
+<label for="password">This link is password protected</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required autofocus>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type prompt struct {
+	Error string
+}
+
+// Unlock checks password submitted from prompt and redirects to target
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
+	link, err := h.resolve(r.Context(), normalizeHost(r.Host), r.PathValue("code"))
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	if !h.available(w, link) {
+		return
+	}
+
+	if link.PasswordHash != "" {
+		r.Body = http.MaxBytesReader(w, r.Body, maxPasswordForm)
+		if err = r.ParseForm(); err != nil {
+			h.WriteError(w, errors.NewValidationError("Invalid password form"))
+			return
+		}
+		if !security.CheckPassword(link.PasswordHash, r.PostFormValue("password")) {
+			h.Log(r).Warn("Wrong short link password", "domain", link.Domain, "code", link.Code)
+			h.renderPrompt(w, r, link, http.StatusUnauthorized, "Wrong password, try again")
+			return
+		}
+	}
+
+	h.follow(w, r, link)
+}
+
+func (h *Handler) renderPrompt(w http.ResponseWriter, r *http.Request, link storage.Link, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	// form posts back to the page URL, so path prefix of proxies is kept
+	if err := promptTemplate.Execute(w, prompt{Error: message}); err != nil {
+		h.Log(r).Error("failed to render password prompt", "code", link.Code, "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package links
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"link_shortener/pkg/errors"
+	"link_shortener/pkg/qr"
+	"link_shortener/pkg/storage"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// qrCacheAge is how long clients may cache rendered codes, seconds
+const qrCacheAge = 3600
+
+// QR renders short URL of link as QR code. Query accepts format=png|svg,
+// falling back to Accept header, size in pixels, margin in modules,
+// error correction level=L|M|Q|H and domain of scoped links
+func (h *Handler) QR(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	domain := normalizeHost(r.URL.Query().Get("domain"))
+
+	link, err := h.storage.LoadLink(r.Context(), domain, code)
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			h.WriteError(w, errors.NewNotFoundError("Short link not found"))
+			return
+		}
+		h.Log(r).Error(errors.NewStorageError(err.Error()).Error())
+		h.WriteError(w, errors.NewStorageError(err.Error()))
+		return
+	}
+
+	format, opts, err := parseQRQuery(r)
+	if err != nil {
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	image, err := qr.Render(h.links.BuildFor(link.Domain, link.Code), format, opts)
+	if err != nil {
+		h.Log(r).Error(errors.Wrap("failed to render qr code", err).Error())
+		h.WriteError(w, errors.NewValidationError(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", qrCacheAge))
+	w.Header().Set("Vary", "Accept")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(image); err != nil {
+		h.Log(r).Warn("failed to write qr code", "code", link.Code, "error", err)
+	}
+}
+
+func parseQRQuery(r *http.Request) (qr.Format, qr.Options, error) {
+	query := r.URL.Query()
+	opts := qr.DefaultOptions()
+
+	format := qr.PNG
+	switch raw := strings.ToLower(query.Get("format")); raw {
+	case "":
+		if prefersSVG(r.Header.Get("Accept")) {
+			format = qr.SVG
+		}
+	case string(qr.PNG), string(qr.SVG):
+		format = qr.Format(raw)
+	default:
+		return "", qr.Options{}, fmt.Errorf("unknown format %q, use png or svg", raw)
+	}
+
+	for name, target := range map[string]*int{"size": &opts.Size, "margin": &opts.Margin} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return "", qr.Options{}, fmt.Errorf("invalid %s: %s", name, raw)
+		}
+		*target = value
+	}
+
+	if level := query.Get("level"); level != "" {
+		opts.Level = strings.ToUpper(level)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return "", qr.Options{}, err
+	}
+
+	return format, opts, nil
+}
+
+// prefersSVG reports whether Accept header lists SVG before PNG
+func prefersSVG(accept string) bool {
+	svg := strings.Index(accept, "image/svg+xml")
+	if svg < 0 {
+		return false
+	}
+	png := strings.Index(accept, "image/png")
+	return png < 0 || svg < png
+}
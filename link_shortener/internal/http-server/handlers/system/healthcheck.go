@@ -1,9 +1,14 @@
 package system
 
 import (
-	"link_shortener"
+	"context"
+	mainversion "link_shortener"
 	"link_shortener/internal/http-server/handlers/base"
+	"link_shortener/internal/services/analytics"
+	"link_shortener/internal/services/email/queue"
+	"link_shortener/internal/services/health"
 	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage/cache"
 	"net/http"
 	"time"
 )
@@ -11,15 +16,54 @@ import (
 const (
 	HealthV1 = "/api/v1/health"
 	Health   = "/health"
+	Metrics  = "/metrics"
+	Livez    = "/livez"
+	Readyz   = "/readyz"
 )
 
 type Handler struct {
 	base.Handler
+	mailQueue MailQueue
+	clicks    ClickRecorder
+	linkCache LinkCache
+	readiness Readiness
+	metrics   http.Handler
 }
 
-func New(mux *http.ServeMux, logger logger.Logger) {
+type MailQueue interface {
+	Stats() queue.Stats
+}
+
+type ClickRecorder interface {
+	Stats() analytics.RecorderStats
+}
+
+type LinkCache interface {
+	Stats() cache.Stats
+}
+
+type Readiness interface {
+	Check(ctx context.Context) health.Report
+}
+
+// Options are optional sources of system routes, nil ones are left out
+type Options struct {
+	MailQueue MailQueue
+	Clicks    ClickRecorder
+	LinkCache LinkCache
+	Readiness Readiness
+	// Metrics serves Prometheus scrapes
+	Metrics http.Handler
+}
+
+func New(mux *http.ServeMux, logger logger.Logger, opts Options) {
 	handler := &Handler{
-		Handler: base.Handler{Logger: logger},
+		Handler:   base.Handler{Logger: logger},
+		mailQueue: opts.MailQueue,
+		clicks:    opts.Clicks,
+		linkCache: opts.LinkCache,
+		readiness: opts.Readiness,
+		metrics:   opts.Metrics,
 	}
 
 	handler.registerRoutes(mux)
@@ -28,15 +72,50 @@ func New(mux *http.ServeMux, logger logger.Logger) {
 func (h *Handler) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET "+HealthV1, h.health)
 	mux.HandleFunc("GET "+Health, h.health)
+	mux.HandleFunc("GET "+Livez, h.live)
+	if h.readiness != nil {
+		mux.HandleFunc("GET "+Readyz, h.ready)
+	}
+	if h.metrics != nil {
+		mux.Handle("GET "+Metrics, h.metrics)
+	}
 }
 
 func (h *Handler) health(w http.ResponseWriter, _ *http.Request) {
 	response := map[string]interface{}{
 		"status":    "OK",
-		"service":   link_shortener.AppName,
+		"service":   mainversion.AppName,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"version":   link_shortener.Version,
-		"buildDate": link_shortener.BuildDate,
+		"version":   mainversion.Version,
+		"buildDate": mainversion.BuildDate,
+	}
+	if h.mailQueue != nil {
+		response["mailQueue"] = h.mailQueue.Stats()
+	}
+	if h.clicks != nil {
+		response["clicks"] = h.clicks.Stats()
+	}
+	if h.linkCache != nil {
+		response["linkCache"] = h.linkCache.Stats()
 	}
 	h.WriteJSON(w, http.StatusOK, response)
 }
+
+// live reports that process serves requests, dependencies are not checked
+// so orchestrator does not restart service because of their outage
+func (h *Handler) live(w http.ResponseWriter, _ *http.Request) {
+	h.WriteJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// ready reports checks of dependencies, failed critical check answers 503
+// so traffic is routed elsewhere
+func (h *Handler) ready(w http.ResponseWriter, r *http.Request) {
+	report := h.readiness.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+		h.Log(r).Warn("Service not ready", "checks", report.Checks)
+	}
+	h.WriteJSON(w, status, report)
+}
@@ -1,23 +1,48 @@
 package server
 
-import "net/http"
+import (
+	"context"
+	"link_shortener/internal/config"
+	"net/http"
+	"time"
+)
 
 type Server struct {
-	Port    string
-	Handler *http.ServeMux
+	Port   string
+	server *http.Server
 }
 
-func New(port string, router *http.ServeMux) *Server {
+// New returns server listening on configured port. Read and write deadlines
+// come from [config.HttpServer] Timeout, header deadline from ReadHeaderTimeout
+func New(cfg config.HttpServer, handler http.Handler) *Server {
+	port := ":" + cfg.Port
 	return &Server{
-		Port:    ":" + port,
-		Handler: router,
+		Port: port,
+		server: &http.Server{
+			Addr:              port,
+			Handler:           handler,
+			ReadTimeout:       cfg.Timeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.Timeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    1 << 20,
+		},
 	}
 }
 
 func (s *Server) ListenAndServe() error {
-	server := &http.Server{
-		Addr:    s.Port,
-		Handler: s.Handler,
+	return s.server.ListenAndServe()
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests
+// until timeout passes, then closes remaining connections
+func (s *Server) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		_ = s.server.Close()
+		return err
 	}
-	return server.ListenAndServe()
+	return nil
 }
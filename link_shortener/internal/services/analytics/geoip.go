@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"net"
+)
+
+// GeoIP resolves coarse location of client, empty country means unknown
+type GeoIP interface {
+	Country(ip net.IP) string
+	Close() error
+}
+
+type noGeoIP struct{}
+
+func (noGeoIP) Country(net.IP) string { return "" }
+func (noGeoIP) Close() error          { return nil }
+
+// maxMind reads country from local MaxMind GeoLite2/GeoIP2 Country or City database
+type maxMind struct {
+	reader *maxminddb.Reader
+}
+
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// NewGeoIP opens database at path, empty path disables lookups
+func NewGeoIP(path string) (GeoIP, error) {
+	if path == "" {
+		return noGeoIP{}, nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database %s: %w", path, err)
+	}
+	return &maxMind{reader: reader}, nil
+}
+
+func (m *maxMind) Country(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	var record countryRecord
+	if err := m.reader.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	return record.Country.ISOCode
+}
+
+func (m *maxMind) Close() error {
+	return m.reader.Close()
+}
@@ -0,0 +1,216 @@
+package analytics
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"link_shortener/internal/config"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/middleware"
+	"link_shortener/pkg/storage"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Store interface {
+	SaveClicks(ctx context.Context, clicks []storage.Click) error
+	LoadClicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error)
+}
+
+// event is click waiting for flush, ip is resolved and hashed off request path
+type event struct {
+	click storage.Click
+	ip    string
+}
+
+// RecorderStats is a snapshot of recorder counters
+type RecorderStats struct {
+	Buffered int   `json:"buffered"`
+	Recorded int64 `json:"recorded"`
+	Dropped  int64 `json:"dropped"`
+	Failed   int64 `json:"failed"`
+}
+
+// Recorder collects clicks without blocking redirects: events go to bounded
+// buffer which background goroutine flushes to store in batches. Events
+// arriving while buffer is full are dropped and counted
+type Recorder struct {
+	store      Store
+	geo        GeoIP
+	config     config.Analytics
+	trustProxy bool
+	salt       []byte
+	logger     logger.Logger
+
+	events chan event
+	done   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+
+	recorded atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+}
+
+func NewRecorder(store Store, geo GeoIP, config config.Analytics, trustProxy bool,
+	logger logger.Logger) *Recorder {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+
+	salt := []byte(config.IPSalt)
+	if len(salt) == 0 {
+		salt = make([]byte, 32)
+		_, _ = rand.Read(salt)
+		logger.Warn("analytics ip salt is not configured, unique visitors will reset on restart")
+	}
+
+	return &Recorder{
+		store:      store,
+		geo:        geo,
+		config:     config,
+		trustProxy: trustProxy,
+		salt:       salt,
+		logger:     logger,
+		events:     make(chan event, config.BufferSize),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start launches flushing goroutine
+func (r *Recorder) Start() {
+	go r.run()
+	r.logger.Debug("click recorder started", "buffer", r.config.BufferSize)
+}
+
+// Track registers redirect of link made by req, never blocks
+func (r *Recorder) Track(domain, code string, req *http.Request) {
+	if !r.config.Enabled {
+		return
+	}
+
+	e := event{
+		click: storage.Click{
+			Domain:    domain,
+			Code:      code,
+			Time:      time.Now().UTC(),
+			Referrer:  req.Referer(),
+			UserAgent: req.UserAgent(),
+		},
+		ip: middleware.ClientIP(req, r.trustProxy),
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		r.dropped.Add(1)
+		return
+	}
+
+	select {
+	case r.events <- e:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Clicks returns clicks of link within [from, to)
+func (r *Recorder) Clicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error) {
+	return r.store.LoadClicks(ctx, domain, code, from, to)
+}
+
+func (r *Recorder) Stats() RecorderStats {
+	return RecorderStats{
+		Buffered: len(r.events),
+		Recorded: r.recorded.Load(),
+		Dropped:  r.dropped.Load(),
+		Failed:   r.failed.Load(),
+	}
+}
+
+// Shutdown stops accepting clicks and flushes buffered ones until ctx expires
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		r.logger.Debug("click recorder flushed")
+	case <-ctx.Done():
+		r.logger.Warn("click recorder flush deadline exceeded", "buffered", len(r.events))
+		return ctx.Err()
+	}
+
+	if err := r.geo.Close(); err != nil {
+		r.logger.Warn("failed to close geoip database", "error", err)
+	}
+	return nil
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]storage.Click, 0, r.config.BatchSize)
+	for {
+		select {
+		case e, ok := <-r.events:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, r.resolve(e))
+			if len(batch) >= r.config.BatchSize {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (r *Recorder) resolve(e event) storage.Click {
+	click := e.click
+	if ip := net.ParseIP(e.ip); ip != nil {
+		click.Country = r.geo.Country(ip)
+		click.IPHash = r.hashIP(ip)
+	}
+	return click
+}
+
+func (r *Recorder) hashIP(ip net.IP) string {
+	h := sha256.New()
+	h.Write(r.salt)
+	h.Write(ip)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+func (r *Recorder) flush(batch []storage.Click) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := r.store.SaveClicks(context.Background(), batch); err != nil {
+		r.failed.Add(int64(len(batch)))
+		r.logger.Error("failed to save clicks", "count", len(batch), "error", err)
+		return
+	}
+	r.recorded.Add(int64(len(batch)))
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"link_shortener/pkg/storage"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// MaxBuckets bounds length of stats series
+const MaxBuckets = 1000
+
+// unknown groups clicks without referrer or resolved country
+const unknown = "unknown"
+
+type Point struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+type Counter struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Stats struct {
+	Code           string    `json:"code"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Bucket         string    `json:"bucket"`
+	Total          int       `json:"total"`
+	UniqueVisitors int       `json:"unique_visitors"`
+	Series         []Point   `json:"series"`
+	Referrers      []Counter `json:"referrers"`
+	Countries      []Counter `json:"countries"`
+}
+
+// Aggregate builds totals and dense series of clicks within [from, to) split
+// into buckets of given size, from is expected to be aligned to bucket
+func Aggregate(code string, clicks []storage.Click, from, to time.Time,
+	bucket time.Duration, bucketName string) Stats {
+	stats := Stats{
+		Code:   code,
+		From:   from,
+		To:     to,
+		Bucket: bucketName,
+	}
+
+	for start := from; start.Before(to); start = start.Add(bucket) {
+		stats.Series = append(stats.Series, Point{Start: start})
+	}
+
+	visitors := make(map[string]struct{})
+	referrers := make(map[string]int)
+	countries := make(map[string]int)
+
+	for _, click := range clicks {
+		if click.Time.Before(from) || !click.Time.Before(to) {
+			continue
+		}
+		stats.Total++
+		stats.Series[int(click.Time.Sub(from)/bucket)].Count++
+
+		if click.IPHash != "" {
+			visitors[click.IPHash] = struct{}{}
+		}
+		referrers[orUnknown(referrerHost(click.Referrer))]++
+		countries[orUnknown(click.Country)]++
+	}
+
+	stats.UniqueVisitors = len(visitors)
+	stats.Referrers = sortedCounters(referrers)
+	stats.Countries = sortedCounters(countries)
+
+	return stats
+}
+
+func sortedCounters(counts map[string]int) []Counter {
+	counters := make([]Counter, 0, len(counts))
+	for value, count := range counts {
+		counters = append(counters, Counter{Value: value, Count: count})
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count != counters[j].Count {
+			return counters[i].Count > counters[j].Count
+		}
+		return counters[i].Value < counters[j].Value
+	})
+	return counters
+}
+
+// referrerHost groups referrers by host to keep report compact
+func referrerHost(referrer string) string {
+	u, err := url.Parse(referrer)
+	if err != nil || u.Host == "" {
+		return referrer
+	}
+	return u.Host
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return unknown
+	}
+	return value
+}
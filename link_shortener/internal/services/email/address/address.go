@@ -0,0 +1,165 @@
+package address
+
+import (
+	"context"
+	stdErrors "errors"
+	"golang.org/x/net/idna"
+	"net"
+	"strings"
+)
+
+// gmailDomains receive mail for the same mailbox, googlemail.com is folded to gmail.com
+var gmailDomains = map[string]struct{}{
+	"gmail.com":      {},
+	"googlemail.com": {},
+}
+
+// Rejection explains why address is not accepted
+type Rejection struct {
+	Reason string
+}
+
+func (r Rejection) Error() string {
+	return r.Reason
+}
+
+// DomainList tells whether domain or one of its parents is listed, e.g.
+// [urlpolicy.Blocklist] loaded with disposable email domains
+type DomainList interface {
+	Contains(domain string) (string, bool)
+}
+
+// MXLookup resolves mail exchangers of domain, [net.Resolver] LookupMX fits
+type MXLookup func(ctx context.Context, domain string) ([]*net.MX, error)
+
+// HostLookup resolves addresses of domain, [net.Resolver] LookupHost fits
+type HostLookup func(ctx context.Context, host string) ([]string, error)
+
+type Options struct {
+	// FoldGmail drops dots and +tags of Gmail local parts so one mailbox
+	// can not verify many addresses
+	FoldGmail bool
+	// Disposable rejects throwaway mailbox domains, nil disables it
+	Disposable DomainList
+	// LookupMX rejects domains without mail exchanger, nil disables it
+	LookupMX MXLookup
+	// LookupHost finds implicit MX of RFC 5321 section 5.1, domain without
+	// MX records still accepts mail on its A or AAAA address. Nil rejects
+	// every domain without MX records
+	LookupHost HostLookup
+}
+
+// Checker normalizes recipient addresses and screens them before emails are sent
+type Checker struct {
+	opts Options
+}
+
+func New(opts Options) *Checker {
+	return &Checker{opts: opts}
+}
+
+// Check returns normalized address or [Rejection]. DNS failures other than
+// missing domain do not reject address, resolver outage must not stop sign ups
+func (c *Checker) Check(ctx context.Context, raw string) (string, error) {
+	address, err := Normalize(raw, c.opts.FoldGmail)
+	if err != nil {
+		return "", err
+	}
+	_, domain, _ := strings.Cut(address, "@")
+
+	if c.opts.Disposable != nil {
+		if listed, ok := c.opts.Disposable.Contains(domain); ok {
+			return "", Rejection{Reason: "disposable email domain " + listed + " is not allowed"}
+		}
+	}
+
+	if c.opts.LookupMX != nil {
+		if err = c.checkMX(ctx, domain); err != nil {
+			return "", err
+		}
+	}
+
+	return address, nil
+}
+
+// Mailbox returns normalized address with local part lowercased and Gmail
+// aliases folded even when checker accepts them as distinct addresses,
+// since practically all providers deliver them to one mailbox. It is the
+// identity of email: verification, owner and per recipient limits are
+// keyed by it, while emails are sent to address Check returns
+func (c *Checker) Mailbox(raw string) (string, error) {
+	address, err := Normalize(raw, true)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(address), nil
+}
+
+func (c *Checker) checkMX(ctx context.Context, domain string) error {
+	records, err := c.opts.LookupMX(ctx, domain)
+	if err != nil && !isNotFound(err) {
+		return nil
+	}
+
+	if len(records) == 0 {
+		return c.checkImplicitMX(ctx, domain)
+	}
+
+	// null MX of RFC 7505 declares that domain accepts no mail
+	if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+		return Rejection{Reason: "email domain " + domain + " does not accept mail"}
+	}
+	return nil
+}
+
+// checkImplicitMX accepts domain without MX records when it has address
+func (c *Checker) checkImplicitMX(ctx context.Context, domain string) error {
+	if c.opts.LookupHost == nil {
+		return Rejection{Reason: "email domain " + domain + " does not accept mail"}
+	}
+
+	addrs, err := c.opts.LookupHost(ctx, domain)
+	switch {
+	case isNotFound(err):
+		return Rejection{Reason: "email domain " + domain + " does not exist"}
+	case err != nil:
+		return nil
+	case len(addrs) == 0:
+		return Rejection{Reason: "email domain " + domain + " does not accept mail"}
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return stdErrors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// Normalize lowercases domain and converts it to punycode, local part is
+// kept as is since it may be case sensitive. With foldGmail Gmail addresses
+// are reduced to canonical mailbox
+func Normalize(raw string, foldGmail bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	at := strings.LastIndex(raw, "@")
+	if at <= 0 || at == len(raw)-1 {
+		return "", Rejection{Reason: "email must have local part and domain"}
+	}
+	local, domain := raw[:at], strings.TrimSuffix(raw[at+1:], ".")
+
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", Rejection{Reason: "email domain is not valid"}
+	}
+	domain = strings.ToLower(domain)
+
+	if _, ok := gmailDomains[domain]; ok && foldGmail {
+		local, _, _ = strings.Cut(local, "+")
+		local = strings.ToLower(strings.ReplaceAll(local, ".", ""))
+		domain = "gmail.com"
+		if local == "" {
+			return "", Rejection{Reason: "email must have local part and domain"}
+		}
+	}
+
+	return local + "@" + domain, nil
+}
@@ -0,0 +1,208 @@
+package address
+
+import (
+	"context"
+	stdErrors "errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		foldGmail bool
+		want      string
+		wantErr   bool
+	}{
+		{name: "domain lowercased", raw: "Bob@Example.COM", want: "Bob@example.com"},
+		{name: "surrounding space trimmed", raw: "  bob@example.com \n", want: "bob@example.com"},
+		{name: "trailing dot of domain dropped", raw: "bob@example.com.", want: "bob@example.com"},
+		{name: "unicode domain to punycode", raw: "bob@bücher.example", want: "bob@xn--bcher-kva.example"},
+		{name: "uppercase unicode domain", raw: "bob@BÜCHER.example", want: "bob@xn--bcher-kva.example"},
+		{name: "gmail kept without folding", raw: "A.B+tag@gmail.com", want: "A.B+tag@gmail.com"},
+		{name: "gmail dots and tag folded", raw: "A.B+tag@gmail.com", foldGmail: true, want: "ab@gmail.com"},
+		{name: "googlemail folded to gmail", raw: "a.b@GoogleMail.com", foldGmail: true, want: "ab@gmail.com"},
+		{name: "other domains not folded", raw: "a.b+tag@example.com", foldGmail: true, want: "a.b+tag@example.com"},
+		{name: "gmail tag only", raw: "+tag@gmail.com", foldGmail: true, wantErr: true},
+		{name: "missing local part", raw: "@example.com", wantErr: true},
+		{name: "missing domain", raw: "bob@", wantErr: true},
+		{name: "missing at", raw: "bob.example.com", wantErr: true},
+		{name: "single label domain", raw: "bob@localhost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.foldGmail)
+			if tt.wantErr {
+				var rejection Rejection
+				if !stdErrors.As(err, &rejection) {
+					t.Fatalf("Normalize(%q) = %q, %v, want Rejection", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMailbox(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []string
+		want string
+	}{
+		{
+			name: "case variants",
+			raw:  []string{"Victim@x.com", "VICTIM@x.com", "victim@X.com", "vIcTiM@X.COM"},
+			want: "victim@x.com",
+		},
+		{
+			name: "gmail case and alias variants",
+			raw:  []string{"A.B+1@gmail.com", "ab+2@GoogleMail.com", "AB@GMAIL.COM"},
+			want: "ab@gmail.com",
+		},
+		{
+			name: "unicode domain",
+			raw:  []string{"Bob@BÜCHER.example", "bob@bücher.example"},
+			want: "bob@xn--bcher-kva.example",
+		},
+	}
+
+	checker := New(Options{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, raw := range tt.raw {
+				got, err := checker.Mailbox(raw)
+				if err != nil {
+					t.Fatalf("Mailbox(%q): %v", raw, err)
+				}
+				if got != tt.want {
+					t.Errorf("Mailbox(%q) = %q, want %q", raw, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMailboxAlwaysFoldsGmail(t *testing.T) {
+	checker := New(Options{})
+	for _, raw := range []string{"a.b+1@gmail.com", "ab+2@googlemail.com", "A.B@gmail.com"} {
+		got, err := checker.Mailbox(raw)
+		if err != nil {
+			t.Fatalf("Mailbox(%q): %v", raw, err)
+		}
+		if got != "ab@gmail.com" {
+			t.Errorf("Mailbox(%q) = %q, want ab@gmail.com", raw, got)
+		}
+	}
+}
+
+// domains is [DomainList] stub listing exact domains and their subdomains
+type domains []string
+
+func (d domains) Contains(domain string) (string, bool) {
+	for _, listed := range d {
+		if domain == listed || strings.HasSuffix(domain, "."+listed) {
+			return listed, true
+		}
+	}
+	return "", false
+}
+
+func lookupMX(records map[string][]*net.MX, errs map[string]error) MXLookup {
+	return func(_ context.Context, domain string) ([]*net.MX, error) {
+		if err, ok := errs[domain]; ok {
+			return nil, err
+		}
+		return records[domain], nil
+	}
+}
+
+func lookupHost(addrs map[string][]string, errs map[string]error) HostLookup {
+	return func(_ context.Context, host string) ([]string, error) {
+		if err, ok := errs[host]; ok {
+			return nil, err
+		}
+		return addrs[host], nil
+	}
+}
+
+func TestCheck(t *testing.T) {
+	notFound := func(name string) error {
+		return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	checker := New(Options{
+		Disposable: domains{"mailinator.com"},
+		LookupMX: lookupMX(
+			map[string][]*net.MX{
+				"example.com": {{Host: "mx.example.com.", Pref: 10}},
+				"nullmx.com":  {{Host: ".", Pref: 0}},
+				"emptymx.com": {{Host: "", Pref: 0}},
+				"nomx.com":    {},
+				"noaddr.com":  {},
+			},
+			map[string]error{
+				"missing.com":   notFound("missing.com"),
+				"nodata.com":    notFound("nodata.com"),
+				"flaky.com":     &net.DNSError{Err: "server misbehaving", Name: "flaky.com", IsTemporary: true},
+				"hostflaky.com": notFound("hostflaky.com"),
+			},
+		),
+		LookupHost: lookupHost(
+			map[string][]string{
+				"nomx.com":   {"192.0.2.10"},
+				"nodata.com": {"2001:db8::10"},
+			},
+			map[string]error{
+				"missing.com":   notFound("missing.com"),
+				"hostflaky.com": &net.DNSError{Err: "server misbehaving", Name: "hostflaky.com", IsTemporary: true},
+			},
+		),
+	})
+
+	tests := []struct {
+		name   string
+		raw    string
+		want   string
+		reject string
+	}{
+		{name: "deliverable", raw: "bob@Example.com", want: "bob@example.com"},
+		{name: "disposable", raw: "bob@mailinator.com", reject: "disposable"},
+		{name: "disposable subdomain", raw: "bob@eu.mailinator.com", reject: "disposable"},
+		{name: "null mx", raw: "bob@nullmx.com", reject: "does not accept mail"},
+		{name: "null mx with empty host", raw: "bob@emptymx.com", reject: "does not accept mail"},
+		{name: "implicit mx of a record", raw: "bob@nomx.com", want: "bob@nomx.com"},
+		{name: "implicit mx when mx not found", raw: "bob@nodata.com", want: "bob@nodata.com"},
+		{name: "no mx and no address", raw: "bob@noaddr.com", reject: "does not accept mail"},
+		{name: "domain does not exist", raw: "bob@missing.com", reject: "does not exist"},
+		{name: "resolver outage accepted", raw: "bob@flaky.com", want: "bob@flaky.com"},
+		{name: "host resolver outage accepted", raw: "bob@hostflaky.com", want: "bob@hostflaky.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checker.Check(context.Background(), tt.raw)
+			if tt.reject != "" {
+				var rejection Rejection
+				if !stdErrors.As(err, &rejection) || !strings.Contains(rejection.Reason, tt.reject) {
+					t.Fatalf("Check(%q) = %q, %v, want rejection %q", tt.raw, got, err, tt.reject)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check(%q): %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Check(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
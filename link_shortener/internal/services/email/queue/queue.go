@@ -0,0 +1,291 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"link_shortener/internal/config"
+	"link_shortener/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DeadLetterFile = "dead_letter.jsonl"
+
+var (
+	ErrQueueFull   = stdErrors.New("mail queue is full")
+	ErrQueueClosed = stdErrors.New("mail queue is closed")
+)
+
+const (
+	KindVerification = "verification"
+	KindConfirmation = "confirmation"
+)
+
+type Sender interface {
+	SendVerificationEmail(to, verificationLink, acceptLanguage string) error
+	SendConfirmationEmail(to, acceptLanguage string) error
+}
+
+// Job is a single outbound email waiting for delivery
+type Job struct {
+	Kind           string    `json:"kind"`
+	To             string    `json:"to"`
+	Link           string    `json:"link,omitempty"`
+	AcceptLanguage string    `json:"accept_language,omitempty"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error,omitempty"`
+	EnqueuedAt     time.Time `json:"enqueued_at"`
+}
+
+// Stats is a snapshot of queue counters
+type Stats struct {
+	Depth        int   `json:"depth"`
+	Capacity     int   `json:"capacity"`
+	InFlight     int64 `json:"in_flight"`
+	Sent         int64 `json:"sent"`
+	Failed       int64 `json:"failed_attempts"`
+	DeadLettered int64 `json:"dead_lettered"`
+}
+
+// Queue delivers emails in background with bounded worker pool. It exposes
+// the same methods as email service, but they only enqueue the message
+type Queue struct {
+	sender Sender
+	config config.MailQueue
+	logger logger.Logger
+
+	jobs   chan Job
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+	dlMu   sync.Mutex
+
+	inFlight     atomic.Int64
+	sent         atomic.Int64
+	failed       atomic.Int64
+	deadLettered atomic.Int64
+}
+
+func New(sender Sender, config config.MailQueue, logger logger.Logger) *Queue {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.Size <= 0 {
+		config.Size = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+	return &Queue{
+		sender: sender,
+		config: config,
+		logger: logger,
+		jobs:   make(chan Job, config.Size),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start launches worker goroutines
+func (q *Queue) Start() {
+	for i := 0; i < q.config.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	q.logger.Debug("mail queue started", "workers", q.config.Workers, "size", q.config.Size)
+}
+
+// Shutdown stops accepting jobs and waits until workers drain the queue.
+// When ctx expires, pending retries are aborted, remaining jobs are
+// written to dead letter file and Shutdown returns without waiting for
+// workers stuck in delivery
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.jobs)
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.logger.Debug("mail queue drained")
+		return nil
+	case <-ctx.Done():
+		close(q.stop)
+		q.logger.Warn("mail queue drain deadline exceeded, remaining jobs dead lettered")
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) SendVerificationEmail(to, verificationLink, acceptLanguage string) error {
+	return q.enqueue(Job{
+		Kind:           KindVerification,
+		To:             to,
+		Link:           verificationLink,
+		AcceptLanguage: acceptLanguage,
+	})
+}
+
+func (q *Queue) SendConfirmationEmail(to, acceptLanguage string) error {
+	return q.enqueue(Job{
+		Kind:           KindConfirmation,
+		To:             to,
+		AcceptLanguage: acceptLanguage,
+	})
+}
+
+func (q *Queue) Stats() Stats {
+	return Stats{
+		Depth:        len(q.jobs),
+		Capacity:     cap(q.jobs),
+		InFlight:     q.inFlight.Load(),
+		Sent:         q.sent.Load(),
+		Failed:       q.failed.Load(),
+		DeadLettered: q.deadLettered.Load(),
+	}
+}
+
+func (q *Queue) enqueue(job Job) error {
+	job.EnqueuedAt = time.Now().UTC()
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.jobs <- job:
+		q.logger.Debug("email enqueued", "kind", job.Kind, "to", job.To)
+		return nil
+	default:
+		q.logger.Warn("mail queue is full", "kind", job.Kind, "to", job.To)
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		select {
+		case <-q.stop:
+			q.deadLetter(job)
+			continue
+		default:
+		}
+
+		q.inFlight.Add(1)
+		q.process(job)
+		q.inFlight.Add(-1)
+	}
+}
+
+// process delivers job retrying with exponential backoff
+func (q *Queue) process(job Job) {
+	for {
+		err := q.deliver(job)
+		job.Attempts++
+		if err == nil {
+			q.sent.Add(1)
+			q.logger.Debug("email delivered", "kind", job.Kind, "to", job.To, "attempts", job.Attempts)
+			return
+		}
+
+		q.failed.Add(1)
+		job.LastError = err.Error()
+		q.logger.Warn("email delivery failed", "kind", job.Kind, "to", job.To,
+			"attempt", job.Attempts, "error", err)
+
+		if job.Attempts >= q.config.MaxAttempts {
+			q.deadLetter(job)
+			return
+		}
+
+		select {
+		case <-time.After(q.backoff(job.Attempts)):
+		case <-q.stop:
+			q.deadLetter(job)
+			return
+		}
+	}
+}
+
+func (q *Queue) deliver(job Job) error {
+	switch job.Kind {
+	case KindVerification:
+		return q.sender.SendVerificationEmail(job.To, job.Link, job.AcceptLanguage)
+	case KindConfirmation:
+		return q.sender.SendConfirmationEmail(job.To, job.AcceptLanguage)
+	default:
+		return fmt.Errorf("unknown job kind: %s", job.Kind)
+	}
+}
+
+// backoff returns delay before next attempt: base * 2^(attempt-1) capped by max
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.config.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if q.config.MaxBackoff > 0 && delay >= q.config.MaxBackoff {
+			return q.config.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// deadLetter appends job to dead letter file so it can be inspected later.
+// Verification link grants owner identity of email and is not written,
+// recipient has to request new one
+func (q *Queue) deadLetter(job Job) {
+	const fn = "internal.services.email.queue.deadLetter"
+	q.deadLettered.Add(1)
+	job.Link = ""
+
+	q.dlMu.Lock()
+	defer q.dlMu.Unlock()
+
+	if err := os.MkdirAll(q.config.DeadLetterDir, 0700); err != nil {
+		q.logger.Error(fmt.Sprintf("%s: %s", fn, err.Error()), "to", job.To)
+		return
+	}
+
+	file, err := os.OpenFile(filepath.Join(q.config.DeadLetterDir, DeadLetterFile),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		q.logger.Error(fmt.Sprintf("%s: %s", fn, err.Error()), "to", job.To)
+		return
+	}
+	defer file.Close()
+
+	// file created by older versions was readable by everyone
+	if err = file.Chmod(0600); err != nil {
+		q.logger.Warn(fmt.Sprintf("%s: %s", fn, err.Error()), "file", file.Name())
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		q.logger.Error(fmt.Sprintf("%s: %s", fn, err.Error()), "to", job.To)
+		return
+	}
+
+	if _, err = file.Write(append(payload, '\n')); err != nil {
+		q.logger.Error(fmt.Sprintf("%s: %s", fn, err.Error()), "to", job.To)
+		return
+	}
+
+	q.logger.Error("email dead lettered", "kind", job.Kind, "to", job.To, "attempts", job.Attempts)
+}
@@ -3,87 +3,84 @@ package email
 import (
 	"fmt"
 	"github.com/jordan-wright/email"
+	mainversion "link_shortener"
 	"link_shortener/internal/config"
+	"link_shortener/internal/services/email/templates"
+	"link_shortener/internal/services/email/transport"
 	"link_shortener/pkg/errors"
 	"link_shortener/pkg/logger"
-	"net/smtp"
 )
 
+const sender = "Link shortener"
+
 type Service struct {
-	config config.MailService
-	logger logger.Logger
+	config    config.MailService
+	templates *templates.Engine
+	transport transport.Transport
+	logger    logger.Logger
+}
+
+// Data is passed to every email template
+type Data struct {
+	AppName string
+	Email   string
+	Link    string
 }
 
-// New returns pointer on *Service
-func New(config config.MailService, logger logger.Logger) *Service {
+// New returns pointer on *Service delivering through transport, see [transport.New]
+func New(config config.MailService, templates *templates.Engine, transport transport.Transport,
+	logger logger.Logger) *Service {
 	return &Service{
-		config: config,
-		logger: logger,
+		config:    config,
+		templates: templates,
+		transport: transport,
+		logger:    logger,
 	}
 }
 
-// SendVerificationEmail method sending structured emails to mailhog or via SMTP protocol
-func (s *Service) SendVerificationEmail(to, verificationLink string) error {
-	subject := "Email Verification Required"
-	body := fmt.Sprintf(`
-		Hello,
-
-		Please verify your email address by clicking the following link:
-		%s
-
-		If you didn't request this verification, please ignore this email.
-
-		Best regards,
-		Link Shortener
-	`, verificationLink)
-
-	return s.sendEmail(to, subject, body)
+// SendVerificationEmail method sending structured emails to mailhog or via SMTP protocol,
+// locale of the message is chosen by acceptLanguage header value
+func (s *Service) SendVerificationEmail(to, verificationLink, acceptLanguage string) error {
+	data := Data{
+		AppName: mainversion.AppName,
+		Email:   to,
+		Link:    verificationLink,
+	}
+	return s.send(to, templates.Verification, acceptLanguage, data)
 }
 
-func (s *Service) SendConfirmationEmail(to string) error {
-	subject := "Email Verified Successfully"
-	body := `
-		Hello,
-
-		Your email address has been successfully verified!
-
-		Thank you for using our service.
+func (s *Service) SendConfirmationEmail(to, acceptLanguage string) error {
+	data := Data{
+		AppName: mainversion.AppName,
+		Email:   to,
+	}
+	return s.send(to, templates.Confirmation, acceptLanguage, data)
+}
 
-		Best regards,
-		Link Shortener
-	`
+func (s *Service) send(to, name, acceptLanguage string, data Data) error {
+	msg, err := s.templates.Render(name, acceptLanguage, data)
+	if err != nil {
+		s.logger.Error("Email template render failed", "template", name, "error", err)
+		return errors.Wrap("email template render failed", err)
+	}
 
-	return s.sendEmail(to, subject, body)
+	return s.sendEmail(to, msg)
 }
 
-func (s *Service) sendEmail(to, subject, body string) error {
-	const sender = "Link shortener"
+func (s *Service) sendEmail(to string, msg templates.Message) error {
 	from := fmt.Sprintf("%s <%s>", sender, s.config.Email)
 
 	e := email.NewEmail()
 	e.From = from
 	e.To = []string{to}
-	e.Subject = subject
-	e.Text = []byte(body)
-
-	if s.config.Name == "mailhog" {
-		err := e.Send(s.config.Address, nil)
-		if err != nil {
-			s.logger.Error("MailHog send failed", err.Error())
-			return errors.Wrap("MailHog send failed", err)
-		}
-		s.logger.Debug("Email sent successfully via MailHog")
-		return nil
-	}
+	e.Subject = msg.Subject
+	e.Text = msg.Text
+	e.HTML = msg.HTML
 
-	s.logger.Debug("Using SMTP for email delivery")
-	auth := smtp.PlainAuth("",
-		s.config.Email, s.config.Password, s.config.Host)
-	err := e.Send(s.config.Address, auth)
-	if err != nil {
-		s.logger.Error("SMTP send failed", err.Error())
-		return errors.Wrap("SMTP send failed", err)
+	if err := s.transport.Send(e); err != nil {
+		s.logger.Error("Email send failed", "transport", fmt.Sprintf("%T", s.transport), "error", err)
+		return errors.Wrap("email send failed", err)
 	}
-	s.logger.Debug("Email sent successfully via SMTP")
+	s.logger.Debug("Email sent successfully", "transport", fmt.Sprintf("%T", s.transport))
 	return nil
 }
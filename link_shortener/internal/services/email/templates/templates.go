@@ -0,0 +1,243 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names known to email service
+const (
+	Verification = "verification"
+	Confirmation = "confirmation"
+)
+
+// Template files are laid out as <locale>/<name>.<kind>.tmpl
+const (
+	kindSubject = "subject"
+	kindText    = "txt"
+	kindHTML    = "html"
+	extension   = ".tmpl"
+)
+
+//go:embed defaults
+var defaults embed.FS
+
+type Message struct {
+	Subject string
+	Text    []byte
+	HTML    []byte
+}
+
+type key struct {
+	locale string
+	name   string
+}
+
+type set struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Engine renders named email templates per locale
+type Engine struct {
+	defaultLocale string
+	sets          map[key]*set
+}
+
+// New loads embedded default templates and then files from overrideDir,
+// which replace defaults with the same locale and name. Empty overrideDir
+// keeps defaults only
+func New(overrideDir, defaultLocale string) (*Engine, error) {
+	const fn = "internal.services.email.templates.New"
+	sources := make(map[string]string)
+
+	embedded, err := fs.Sub(defaults, "defaults")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if err = collect(embedded, sources); err != nil {
+		return nil, fmt.Errorf("%s: embedded templates: %w", fn, err)
+	}
+
+	if overrideDir != "" {
+		if err = collect(os.DirFS(overrideDir), sources); err != nil {
+			return nil, fmt.Errorf("%s: templates from %s: %w", fn, overrideDir, err)
+		}
+	}
+
+	e := &Engine{
+		defaultLocale: normalizeLocale(defaultLocale),
+		sets:          make(map[key]*set),
+	}
+	if e.defaultLocale == "" {
+		e.defaultLocale = "en"
+	}
+
+	for file, content := range sources {
+		if err = e.parse(file, content); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+
+	for k, s := range e.sets {
+		if s.subject == nil || s.text == nil {
+			return nil, fmt.Errorf("%s: template %s/%s requires subject and txt parts",
+				fn, k.locale, k.name)
+		}
+	}
+
+	return e, nil
+}
+
+// Render executes template name for locale negotiated from acceptLanguage,
+// falling back to default locale when no better match exists
+func (e *Engine) Render(name, acceptLanguage string, data any) (Message, error) {
+	const fn = "internal.services.email.templates.Render"
+	s, ok := e.lookup(name, acceptLanguage)
+	if !ok {
+		return Message{}, fmt.Errorf("%s: unknown template %s", fn, name)
+	}
+
+	var msg Message
+	var buf bytes.Buffer
+
+	if err := s.subject.Execute(&buf, data); err != nil {
+		return Message{}, fmt.Errorf("%s: %s subject: %w", fn, name, err)
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := s.text.Execute(&buf, data); err != nil {
+		return Message{}, fmt.Errorf("%s: %s text: %w", fn, name, err)
+	}
+	msg.Text = bytes.Clone(buf.Bytes())
+
+	if s.html != nil {
+		buf.Reset()
+		if err := s.html.Execute(&buf, data); err != nil {
+			return Message{}, fmt.Errorf("%s: %s html: %w", fn, name, err)
+		}
+		msg.HTML = bytes.Clone(buf.Bytes())
+	}
+
+	return msg, nil
+}
+
+func (e *Engine) lookup(name, acceptLanguage string) (*set, bool) {
+	for _, locale := range ParseAcceptLanguage(acceptLanguage) {
+		if s, ok := e.sets[key{locale: locale, name: name}]; ok {
+			return s, true
+		}
+	}
+	s, ok := e.sets[key{locale: e.defaultLocale, name: name}]
+	return s, ok
+}
+
+func (e *Engine) parse(file, content string) error {
+	locale, rest := path.Split(file)
+	locale = normalizeLocale(strings.TrimSuffix(locale, "/"))
+	parts := strings.Split(strings.TrimSuffix(rest, extension), ".")
+	if locale == "" || len(parts) != 2 {
+		return fmt.Errorf("unexpected template file %s, want <locale>/<name>.<kind>%s", file, extension)
+	}
+	k := key{locale: locale, name: parts[0]}
+
+	s, ok := e.sets[k]
+	if !ok {
+		s = &set{}
+		e.sets[k] = s
+	}
+
+	var err error
+	switch parts[1] {
+	case kindSubject:
+		s.subject, err = texttemplate.New(file).Parse(content)
+	case kindText:
+		s.text, err = texttemplate.New(file).Parse(content)
+	case kindHTML:
+		s.html, err = htmltemplate.New(file).Parse(content)
+	default:
+		return fmt.Errorf("unknown template kind in %s", file)
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", file, err)
+	}
+	return nil
+}
+
+// collect reads all template files of fsys into sources keyed by relative path,
+// overwriting already collected entries
+func collect(fsys fs.FS, sources map[string]string) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != extension {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		sources[p] = string(content)
+		return nil
+	})
+}
+
+// ParseAcceptLanguage returns locales from Accept-Language header value ordered
+// by preference. Each tag is followed by its primary language, so "en-US" also
+// matches templates stored under "en"
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = normalizeLocale(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{locale: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	locales := make([]string, 0, len(tags)*2)
+	for _, t := range tags {
+		locales = append(locales, t.locale)
+		if primary, _, ok := strings.Cut(t.locale, "-"); ok {
+			locales = append(locales, primary)
+		}
+	}
+	return locales
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
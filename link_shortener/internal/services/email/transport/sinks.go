@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/jordan-wright/email"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File writes every message as .eml file into directory, handy for local
+// development without mail server
+type File struct {
+	dir string
+}
+
+func NewFile(dir string) (*File, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("file transport requires directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir}, nil
+}
+
+func (t *File) Send(message *email.Email) error {
+	payload, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	// message appears under final name only when complete
+	tmp := filepath.Join(t.dir, "."+name+".tmp")
+	if err = os.WriteFile(tmp, payload, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(t.dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Memory keeps sent messages so tests can inspect them
+type Memory struct {
+	mu       sync.Mutex
+	messages []*email.Email
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (t *Memory) Send(message *email.Email) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sent := *message
+	t.messages = append(t.messages, &sent)
+	return nil
+}
+
+// Messages returns messages sent so far in order of sending
+func (t *Memory) Messages() []*email.Email {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*email.Email(nil), t.messages...)
+}
+
+// Reset forgets sent messages
+func (t *Memory) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"fmt"
+	"github.com/jordan-wright/email"
+	"link_shortener/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newMessage(to, subject string) *email.Email {
+	message := email.NewEmail()
+	message.From = "noreply@example.com"
+	message.To = []string{to}
+	message.Subject = subject
+	message.Text = []byte("body of " + subject)
+	return message
+}
+
+func TestMemoryRecordsMessagesInOrder(t *testing.T) {
+	sink := NewMemory()
+
+	first := newMessage("a@example.com", "first")
+	if err := sink.Send(first); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if err := sink.Send(newMessage("b@example.com", "second")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	// sender reusing its message must not alter what was recorded
+	first.Subject = "changed"
+
+	messages := sink.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	for i, want := range []struct{ to, subject string }{
+		{"a@example.com", "first"},
+		{"b@example.com", "second"},
+	} {
+		if got := messages[i].To[0]; got != want.to {
+			t.Errorf("message %d to = %q, want %q", i, got, want.to)
+		}
+		if got := messages[i].Subject; got != want.subject {
+			t.Errorf("message %d subject = %q, want %q", i, got, want.subject)
+		}
+	}
+
+	// returned slice is a snapshot
+	messages[0] = nil
+	if sink.Messages()[0] == nil {
+		t.Error("Messages exposes internal slice")
+	}
+}
+
+func TestMemoryReset(t *testing.T) {
+	sink := NewMemory()
+	if err := sink.Send(newMessage("a@example.com", "first")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	sink.Reset()
+
+	if got := len(sink.Messages()); got != 0 {
+		t.Fatalf("got %d messages after Reset, want 0", got)
+	}
+}
+
+func TestFileWritesCompleteMessages(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFile(dir)
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+
+	if err = sink.Send(newMessage("a@example.com", "hello")); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || filepath.Ext(entries[0].Name()) != ".eml" {
+		t.Fatalf("got entries %v, want single .eml file", entries)
+	}
+
+	payload, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(payload), "Subject: hello") {
+		t.Errorf("message file lacks subject:\n%s", payload)
+	}
+}
+
+func TestNewPicksTransport(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.MailService
+		want string
+	}{
+		{"memory", config.MailService{Transport: config.MailTransportMemory}, "*transport.Memory"},
+		{"file", config.MailService{Transport: config.MailTransportFile, Dir: t.TempDir()}, "*transport.File"},
+		{"legacy mailhog name", config.MailService{Name: config.MailTransportMailHog, Address: "localhost:1025"}, "*transport.MailHog"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if name := fmt.Sprintf("%T", got); name != tt.want {
+				t.Errorf("New picked %s, want %s", name, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(config.MailService{Transport: "pigeon"}); err == nil {
+		t.Error("New accepted unknown transport")
+	}
+}
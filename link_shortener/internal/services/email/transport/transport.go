@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/jordan-wright/email"
+	"link_shortener/internal/config"
+	"net"
+	"net/smtp"
+)
+
+// Transport delivers composed email message
+type Transport interface {
+	Send(message *email.Email) error
+}
+
+// New picks transport by [config.MailService] Transport, empty one keeps
+// legacy behaviour where mailhog name selects MailHog and anything else SMTP
+func New(cfg config.MailService) (Transport, error) {
+	kind := cfg.Transport
+	if kind == "" {
+		kind = config.MailTransportSMTP
+		if cfg.Name == config.MailTransportMailHog {
+			kind = config.MailTransportMailHog
+		}
+	}
+
+	switch kind {
+	case config.MailTransportSMTP:
+		return NewSMTP(cfg.Address, cfg.Email, cfg.Password, cfg.Host, cfg.TLS)
+	case config.MailTransportMailHog:
+		return NewMailHog(cfg.Address), nil
+	case config.MailTransportFile:
+		return NewFile(cfg.Dir)
+	case config.MailTransportMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", kind)
+	}
+}
+
+// Dialer is transport talking to mail server over network, its address
+// is probed by readiness checks
+type Dialer interface {
+	Address() string
+}
+
+// SMTP sends through authenticated SMTP server
+type SMTP struct {
+	address string
+	auth    smtp.Auth
+	tlsMode string
+	tls     *tls.Config
+}
+
+// NewSMTP returns SMTP transport, tlsMode is one of [config.MailTLSOpportunistic],
+// [config.MailTLSStartTLS] requiring upgrade or [config.MailTLSImplicit]
+func NewSMTP(address, username, password, host, tlsMode string) (*SMTP, error) {
+	if address == "" {
+		return nil, fmt.Errorf("smtp transport requires address")
+	}
+	switch tlsMode {
+	case "":
+		tlsMode = config.MailTLSOpportunistic
+	case config.MailTLSOpportunistic, config.MailTLSStartTLS, config.MailTLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown mail tls mode: %s", tlsMode)
+	}
+
+	serverName := host
+	if name, _, err := net.SplitHostPort(address); err == nil && serverName == "" {
+		serverName = name
+	}
+
+	return &SMTP{
+		address: address,
+		auth:    smtp.PlainAuth("", username, password, host),
+		tlsMode: tlsMode,
+		tls:     &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12},
+	}, nil
+}
+
+func (t *SMTP) Send(message *email.Email) error {
+	switch t.tlsMode {
+	case config.MailTLSStartTLS:
+		return message.SendWithStartTLS(t.address, t.auth, t.tls)
+	case config.MailTLSImplicit:
+		return message.SendWithTLS(t.address, t.auth, t.tls)
+	default:
+		// net/smtp upgrades connection when server offers STARTTLS
+		return message.Send(t.address, t.auth)
+	}
+}
+
+func (t *SMTP) Address() string {
+	return t.address
+}
+
+// MailHog sends to MailHog or similar catcher without authentication
+type MailHog struct {
+	address string
+}
+
+func NewMailHog(address string) *MailHog {
+	return &MailHog{address: address}
+}
+
+func (t *MailHog) Send(message *email.Email) error {
+	return message.Send(t.address, nil)
+}
+
+func (t *MailHog) Address() string {
+	return t.address
+}
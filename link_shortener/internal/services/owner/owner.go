@@ -0,0 +1,126 @@
+package owner
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/security"
+	"link_shortener/pkg/storage"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionCookie carries API key for browser clients
+	SessionCookie = "ls_session"
+	// APIKeyHeader carries API key for API clients, Authorization: Bearer works too
+	APIKeyHeader = "X-API-Key"
+
+	sessionMaxAge = 30 * 24 * time.Hour
+)
+
+var ErrUnauthenticated = stdErrors.New("invalid api key")
+
+type Store interface {
+	SaveOwnerKey(ctx context.Context, key storage.OwnerKey) error
+	LoadOwnerKey(ctx context.Context, hash string) (storage.OwnerKey, error)
+	// RevokeOwnerKeys deletes keys of email except the one with hash keep
+	RevokeOwnerKeys(ctx context.Context, email, keep string) error
+}
+
+// Service turns verified emails into owner identities: verification mints
+// API key, requests carrying it act on behalf of the email
+type Service struct {
+	store        Store
+	secureCookie bool
+	logger       logger.Logger
+}
+
+// New creates owner service, secureCookie restricts session cookie to https
+func New(store Store, secureCookie bool, logger logger.Logger) *Service {
+	return &Service{
+		store:        store,
+		secureCookie: secureCookie,
+		logger:       logger,
+	}
+}
+
+// Issue mints new API key for verified email, which is expected in
+// mailbox form of address.Checker. Only key hash is stored so the key is
+// shown to the owner once. Every email has single key,
+// issuing revokes keys minted before, so repeated verification rotates
+// the key instead of piling up valid ones
+func (s *Service) Issue(ctx context.Context, email string) (string, error) {
+	const fn = "internal.services.owner.Issue"
+	key, err := security.NewAPIKey()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	record := storage.OwnerKey{
+		Hash:      security.HashAPIKey(key),
+		Email:     email,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = s.store.SaveOwnerKey(ctx, record); err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = s.store.RevokeOwnerKeys(ctx, record.Email, record.Hash); err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	logger.FromContext(ctx, s.logger).Debug("owner key issued", "email", email)
+
+	return key, nil
+}
+
+// Authenticate resolves owner email from API key carried by r. Requests
+// without key are anonymous and get empty owner, unknown key is
+// [ErrUnauthenticated]
+func (s *Service) Authenticate(r *http.Request) (string, error) {
+	const fn = "internal.services.owner.Authenticate"
+	key := KeyFromRequest(r)
+	if key == "" {
+		return "", nil
+	}
+
+	record, err := s.store.LoadOwnerKey(r.Context(), security.HashAPIKey(key))
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			return "", ErrUnauthenticated
+		}
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return record.Email, nil
+}
+
+// SetSession stores key in http-only cookie so browsers act as owner
+func (s *Service) SetSession(w http.ResponseWriter, key string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    key,
+		Path:     "/",
+		MaxAge:   int(sessionMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// KeyFromRequest returns API key from header, bearer token or session cookie
+func KeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
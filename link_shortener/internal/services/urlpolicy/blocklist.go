@@ -0,0 +1,142 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"link_shortener/pkg/logger"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Blocklist rejects targets whose host or any parent domain is listed in
+// file. File holds one domain per line, "#" starts comment. It is reloaded
+// when its modification time or size changes, checked at most once per interval
+type Blocklist struct {
+	path     string
+	interval time.Duration
+	logger   logger.Logger
+
+	mu        sync.RWMutex
+	domains   map[string]struct{}
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+// NewBlocklist loads blocklist file at path, file must exist
+func NewBlocklist(path string, interval time.Duration, logger logger.Logger) (*Blocklist, error) {
+	const fn = "internal.services.urlpolicy.NewBlocklist"
+	b := &Blocklist{
+		path:     path,
+		interval: interval,
+		logger:   logger,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if err = b.load(info); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return b, nil
+}
+
+func (b *Blocklist) Check(_ context.Context, target *url.URL) error {
+	if domain, ok := b.Contains(target.Hostname()); ok {
+		return Violation{Rule: "blocklist", Reason: "domain " + domain + " is blocked"}
+	}
+	return nil
+}
+
+// Contains reports whether host or any of its parent domains is listed
+// and returns the listed one
+func (b *Blocklist) Contains(host string) (string, bool) {
+	b.reloadIfChanged()
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for domain := host; domain != ""; {
+		if _, ok := b.domains[domain]; ok {
+			return domain, true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return "", false
+}
+
+// Size returns number of blocked domains
+func (b *Blocklist) Size() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.domains)
+}
+
+func (b *Blocklist) reloadIfChanged() {
+	now := time.Now()
+
+	b.mu.Lock()
+	if now.Sub(b.checkedAt) < b.interval {
+		b.mu.Unlock()
+		return
+	}
+	b.checkedAt = now
+	modTime, size := b.modTime, b.size
+	b.mu.Unlock()
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		b.logger.Warn("blocklist is not accessible, keeping loaded domains", "path", b.path, "error", err)
+		return
+	}
+	if info.ModTime().Equal(modTime) && info.Size() == size {
+		return
+	}
+
+	if err = b.load(info); err != nil {
+		b.logger.Warn("failed to reload blocklist, keeping loaded domains", "path", b.path, "error", err)
+	}
+}
+
+func (b *Blocklist) load(info os.FileInfo) error {
+	file, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(line)), ".")
+		if line != "" {
+			domains[line] = struct{}{}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.domains = domains
+	b.modTime = info.ModTime()
+	b.size = info.Size()
+	b.checkedAt = time.Now()
+	b.mu.Unlock()
+
+	b.logger.Info("blocklist loaded", "path", b.path, "domains", len(domains))
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package urlpolicy
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// internalSuffixes are host names never reachable from public internet
+var internalSuffixes = []string{".localhost", ".local", ".internal", ".lan", ".home.arpa"}
+
+// Schemes allows only targets with listed schemes
+func Schemes(schemes ...string) Checker {
+	allowed := make(map[string]struct{}, len(schemes))
+	for _, scheme := range schemes {
+		allowed[strings.ToLower(strings.TrimSpace(scheme))] = struct{}{}
+	}
+
+	return CheckerFunc(func(_ context.Context, target *url.URL) error {
+		if _, ok := allowed[strings.ToLower(target.Scheme)]; !ok {
+			return Violation{Rule: "scheme", Reason: "scheme " + target.Scheme + " is not allowed"}
+		}
+		return nil
+	})
+}
+
+// PrivateHosts rejects loopback, private, link-local and other non-public
+// targets. With resolver set host names are resolved and every address
+// is checked, otherwise only IP literals and well-known internal names are
+func PrivateHosts(resolver *net.Resolver) Checker {
+	return CheckerFunc(func(ctx context.Context, target *url.URL) error {
+		host := strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+
+		if ip := net.ParseIP(host); ip != nil {
+			return checkIP(ip)
+		}
+
+		// browsers take host ending in number for IPv4 in inet_aton forms,
+		// e.g. http://2130706433/, http://127.1/ or http://0x7f.0.0.1/
+		if endsInNumber(host) {
+			return Violation{Rule: "private", Reason: "numeric host " + host + " is not allowed"}
+		}
+
+		if host == "localhost" || !strings.Contains(host, ".") {
+			return Violation{Rule: "private", Reason: "host " + host + " is not public"}
+		}
+		for _, suffix := range internalSuffixes {
+			if strings.HasSuffix(host, suffix) {
+				return Violation{Rule: "private", Reason: "host " + host + " is not public"}
+			}
+		}
+
+		if resolver == nil {
+			return nil
+		}
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return Violation{Rule: "private", Reason: "host " + host + " does not resolve"}
+		}
+		for _, addr := range addrs {
+			if err = checkIP(addr.IP); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func checkIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return Violation{Rule: "private", Reason: "address " + ip.String() + " is not public"}
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return Violation{Rule: "private", Reason: "address " + ip.String() + " is not public"}
+		}
+	}
+	return nil
+}
+
+// endsInNumber follows WHATWG URL host parser, host whose last label is
+// decimal or 0x prefixed hex number is parsed as IPv4 address, labels
+// before may be octal, hex or decimal and missing ones fill with zeros
+func endsInNumber(host string) bool {
+	last := host[strings.LastIndex(host, ".")+1:]
+	if last == "" {
+		return false
+	}
+	if hex, ok := strings.CutPrefix(last, "0x"); ok {
+		return strings.Trim(hex, "0123456789abcdef") == ""
+	}
+	return strings.Trim(last, "0123456789") == ""
+}
+
+// nonPublicNetworks are not covered by [net.IP] predicates
+var nonPublicNetworks = parseNetworks(
+	"0.0.0.0/8",     // "this network"
+	"100.64.0.0/10", // carrier-grade NAT shared space
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
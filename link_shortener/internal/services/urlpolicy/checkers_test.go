@@ -0,0 +1,61 @@
+package urlpolicy
+
+import (
+	"context"
+	"net/url"
+	"testing"
+)
+
+func TestPrivateHosts(t *testing.T) {
+	tests := []struct {
+		raw     string
+		private bool
+	}{
+		{raw: "https://example.com/", private: false},
+		{raw: "https://8.8.8.8/", private: false},
+		{raw: "https://[2001:4860:4860::8888]/", private: false},
+		{raw: "https://1password.com/", private: false},
+		{raw: "https://example.123abc/", private: false},
+
+		{raw: "http://127.0.0.1/", private: true},
+		{raw: "http://10.0.0.1/", private: true},
+		{raw: "http://[::1]/", private: true},
+		{raw: "http://[::ffff:127.0.0.1]/", private: true},
+		{raw: "http://169.254.169.254/", private: true},
+		{raw: "http://localhost/", private: true},
+		{raw: "http://intranet/", private: true},
+		{raw: "http://printer.local/", private: true},
+
+		// inet_aton forms browsers open as IPv4
+		{raw: "http://2130706433/", private: true},
+		{raw: "http://127.1/", private: true},
+		{raw: "http://10.1/", private: true},
+		{raw: "http://192.168.257/", private: true},
+		{raw: "http://0x7f.0.0.1/", private: true},
+		{raw: "http://0X7F.0.0.1/", private: true},
+		{raw: "http://0177.0.0.1/", private: true},
+		{raw: "http://0x7f000001/", private: true},
+		{raw: "http://127.0.0.1./", private: true},
+		{raw: "http://1.2.3.0x/", private: true},
+
+		{raw: "http://0.1.2.3/", private: true},
+		{raw: "http://100.64.0.1/", private: true},
+		{raw: "http://100.127.255.254/", private: true},
+		{raw: "http://100.128.0.1/", private: false},
+	}
+
+	checker := PrivateHosts(nil)
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			target, err := url.Parse(tt.raw)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			err = checker.Check(context.Background(), target)
+			if _, private := AsViolation(err); private != tt.private {
+				t.Errorf("Check(%s) = %v, want private %t", tt.raw, err, tt.private)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package urlpolicy
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Checker inspects target URL of link before it is persisted, returning
+// [Violation] rejects the URL, any other error aborts the check
+type Checker interface {
+	Check(ctx context.Context, target *url.URL) error
+}
+
+// CheckerFunc adapts function to [Checker]
+type CheckerFunc func(ctx context.Context, target *url.URL) error
+
+func (f CheckerFunc) Check(ctx context.Context, target *url.URL) error {
+	return f(ctx, target)
+}
+
+// Violation is rejection of target URL by named rule
+type Violation struct {
+	Rule   string
+	Reason string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Reason)
+}
+
+// AsViolation reports whether err is rejection of URL
+func AsViolation(err error) (Violation, bool) {
+	var violation Violation
+	ok := stdErrors.As(err, &violation)
+	return violation, ok
+}
+
+// Pipeline runs checkers in order of registration and stops at first failure
+type Pipeline struct {
+	mu       sync.RWMutex
+	checkers []Checker
+}
+
+func New(checkers ...Checker) *Pipeline {
+	return &Pipeline{checkers: checkers}
+}
+
+// Use appends checker to pipeline, it is safe to call while serving requests
+func (p *Pipeline) Use(checker Checker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checkers = append(p.checkers, checker)
+}
+
+// Check parses raw URL and runs every checker against it
+func (p *Pipeline) Check(ctx context.Context, raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return Violation{Rule: "syntax", Reason: "malformed url"}
+	}
+	if target.Hostname() == "" {
+		return Violation{Rule: "syntax", Reason: "url has no host"}
+	}
+
+	p.mu.RLock()
+	checkers := p.checkers
+	p.mu.RUnlock()
+
+	for _, checker := range checkers {
+		if err = checker.Check(ctx, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
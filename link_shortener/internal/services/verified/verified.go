@@ -0,0 +1,82 @@
+package verified
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"time"
+)
+
+type Store interface {
+	SaveVerifiedEmail(ctx context.Context, verified storage.VerifiedEmail) error
+	LoadVerifiedEmail(ctx context.Context, email string) (storage.VerifiedEmail, error)
+	ListVerifiedEmails(ctx context.Context, offset, limit int) ([]storage.VerifiedEmail, error)
+}
+
+// Registry remembers emails which completed verification, so features
+// can require verified address after verification record is gone.
+// Emails are expected in mailbox form, see address.Checker Mailbox
+type Registry struct {
+	store  Store
+	logger logger.Logger
+}
+
+func New(store Store, logger logger.Logger) *Registry {
+	return &Registry{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Record marks email verified now from client ip, repeated verification
+// refreshes timestamp and ip
+func (r *Registry) Record(ctx context.Context, email, ip string) error {
+	const fn = "internal.services.verified.Record"
+	err := r.store.SaveVerifiedEmail(ctx, storage.VerifiedEmail{
+		Email:      email,
+		VerifiedAt: time.Now().UTC(),
+		IP:         ip,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	logger.FromContext(ctx, r.logger).Debug("verified email recorded", "email", email)
+
+	return nil
+}
+
+// Lookup returns registry entry of email, unverified email is [storage.ErrNotFound]
+func (r *Registry) Lookup(ctx context.Context, email string) (storage.VerifiedEmail, error) {
+	const fn = "internal.services.verified.Lookup"
+	verified, err := r.store.LoadVerifiedEmail(ctx, email)
+	if err != nil {
+		return storage.VerifiedEmail{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	return verified, nil
+}
+
+// IsVerified reports whether email has ever been verified
+func (r *Registry) IsVerified(ctx context.Context, email string) (bool, error) {
+	const fn = "internal.services.verified.IsVerified"
+	_, err := r.store.LoadVerifiedEmail(ctx, email)
+	if err != nil {
+		if stdErrors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: %w", fn, err)
+	}
+	return true, nil
+}
+
+// List returns registry page ordered by email
+func (r *Registry) List(ctx context.Context, offset, limit int) ([]storage.VerifiedEmail, error) {
+	const fn = "internal.services.verified.List"
+	registry, err := r.store.ListVerifiedEmails(ctx, offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return registry, nil
+}
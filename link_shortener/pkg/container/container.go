@@ -1,24 +1,79 @@
 package container
 
 import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	mainversion "link_shortener"
 	"link_shortener/internal/config"
+	"link_shortener/internal/services/analytics"
 	"link_shortener/internal/services/email"
+	"link_shortener/internal/services/email/address"
+	"link_shortener/internal/services/email/queue"
+	"link_shortener/internal/services/email/templates"
+	"link_shortener/internal/services/email/transport"
+	"link_shortener/internal/services/health"
+	"link_shortener/internal/services/owner"
+	"link_shortener/internal/services/urlpolicy"
+	"link_shortener/internal/services/verified"
 	"link_shortener/pkg/errors"
+	"link_shortener/pkg/linkbuilder"
 	"link_shortener/pkg/logger"
+	"link_shortener/pkg/metrics"
+	"link_shortener/pkg/ratelimit"
 	"link_shortener/pkg/security"
+	"link_shortener/pkg/storage"
+	"link_shortener/pkg/storage/cache"
 	st "link_shortener/pkg/storage/local_storage"
+	pg "link_shortener/pkg/storage/postgres_storage"
 	v "link_shortener/pkg/validator"
+	"net"
+	"strings"
+	"time"
 )
 
 type Service interface {
-	SendVerificationEmail(to string, verificationLink string) error
-	SendConfirmationEmail(to string) error
+	SendVerificationEmail(to, verificationLink, acceptLanguage string) error
+	SendConfirmationEmail(to, acceptLanguage string) error
+}
+
+type HashService interface {
+	GetHash(email string) string
 }
 
 type Storage interface {
-	Save(email string, hash string) error
-	Load(hash string) (map[string]string, error)
-	Delete(hash string) error
+	Save(ctx context.Context, email string, hash string) error
+	Load(ctx context.Context, hash string) (map[string]string, error)
+	Delete(ctx context.Context, hash string) error
+	SaveEmailIndex(ctx context.Context, index storage.EmailIndex) error
+	LoadEmailIndex(ctx context.Context, email string) (storage.EmailIndex, error)
+}
+
+type LinkStorage interface {
+	SaveLink(ctx context.Context, link storage.Link) error
+	LoadLink(ctx context.Context, domain, code string) (storage.Link, error)
+	UpdateLink(ctx context.Context, link storage.Link) error
+	CountRedirect(ctx context.Context, domain, code string) error
+	DeleteLink(ctx context.Context, domain, code string) error
+	ListLinks(ctx context.Context, owner string) ([]storage.Link, error)
+	EachLink(ctx context.Context, owner string, visit func(link storage.Link) error) error
+}
+
+type ClickStorage interface {
+	SaveClicks(ctx context.Context, clicks []storage.Click) error
+	LoadClicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error)
+}
+
+// Backend is storage implementation serving both verification records and links
+type Backend interface {
+	Storage
+	LinkStorage
+	ClickStorage
+	owner.Store
+	verified.Store
+	Purger
+	Pinger
 }
 
 type Validator interface {
@@ -29,9 +84,23 @@ type Container struct {
 	Config       *config.Config
 	Logger       logger.Logger
 	EmailService Service
-	HashService  *security.Hash
+	MailQueue    *queue.Queue
+	Analytics    *analytics.Recorder
+	Owners       *owner.Service
+	Addresses    *address.Checker
+	Verified     *verified.Registry
+	URLPolicy    *urlpolicy.Pipeline
+	HashService  HashService
 	Storage      Storage
+	LinkStorage  LinkStorage
+	LinkCache    *cache.Links
+	CodeService  *security.Code
 	Validator    Validator
+	LinkBuilder  *linkbuilder.Builder
+	RateLimits   ratelimit.Store
+	Metrics      *metrics.Registry
+	Readiness    *health.Readiness
+	sweeper      *sweeper
 }
 
 // New initiate new container with all dependencies needed to run the program
@@ -39,23 +108,248 @@ func New(config *config.Config) (*Container, error) {
 	slog := logger.NewLogger(config.Env.String())
 	appLogger := logger.NewSmartWrapper(slog)
 
-	service := email.New(config.MailService, appLogger)
+	engine, err := templates.New(config.MailService.TemplatesDir, config.MailService.DefaultLocale)
+	if err != nil {
+		return nil, errors.Wrap("could not load email templates", err)
+	}
+
+	registry := metrics.NewRegistry()
+	registry.Gauge("link_shortener_build_info", "Build of running service, value is always 1.",
+		"app", "version", "build_date").Set(1, mainversion.AppName, mainversion.Version, mainversion.BuildDate)
+
+	mailTransport, err := transport.New(config.MailService)
+	if err != nil {
+		return nil, errors.Wrap("could not create mail transport", err)
+	}
+
+	service := newInstrumentedSender(email.New(config.MailService, engine, mailTransport, appLogger), registry)
 
-	hashService := security.NewHashHandler()
+	mailQueue := queue.New(service, config.MailQueue, appLogger)
+	mailQueue.Start()
 
-	storage, err := st.New(config.Env.String(), appLogger)
+	hashService, err := newHashService(config.Verification)
+	if err != nil {
+		return nil, errors.Wrap("could not create hash service", err)
+	}
+
+	codeService := security.NewCodeGenerator(security.DefaultCodeLength)
+
+	backend, err := newStorage(config, appLogger)
 	if err != nil {
 		return nil, errors.Wrap("could not create dbStorage", err)
 	}
+	storage := newInstrumentedStorage(backend, registry)
+
+	linkCache := cache.New(storage, cache.Options{
+		Size:        config.LinkCache.Size,
+		TTL:         config.LinkCache.TTL,
+		NegativeTTL: config.LinkCache.NegativeTTL,
+	}, appLogger)
 
-	validator := &v.StructValidator{}
+	validator := &v.StructValidator{Reserved: config.Links.Reserved}
+
+	rateLimits := ratelimit.NewMemoryStore(max(config.RateLimit.IPPeriod, config.RateLimit.EmailPeriod))
+
+	linkBuilder, err := linkbuilder.New(config.HttpServer)
+	if err != nil {
+		return nil, errors.Wrap("could not create link builder", err)
+	}
+
+	geo, err := analytics.NewGeoIP(config.Analytics.GeoIPPath)
+	if err != nil {
+		return nil, errors.Wrap("could not open geoip database", err)
+	}
+	recorder := analytics.NewRecorder(storage, geo, config.Analytics, config.HttpServer.TrustProxy, appLogger)
+	recorder.Start()
+
+	policy, err := newURLPolicy(config.URLPolicy, appLogger)
+	if err != nil {
+		return nil, errors.Wrap("could not create url policy", err)
+	}
+
+	addresses, err := newAddressChecker(config.EmailPolicy, appLogger)
+	if err != nil {
+		return nil, errors.Wrap("could not create email address checker", err)
+	}
+
+	owners := owner.New(storage, strings.HasPrefix(linkBuilder.Base(), "https://"), appLogger)
+
+	verifiedEmails := verified.New(storage, appLogger)
+
+	registerStats(registry, mailQueue, recorder, linkCache)
+
+	readiness := newReadiness(config, backend, mailTransport)
+
+	sweeper := newSweeper(storage, config.Verification.SweepInterval, appLogger)
+	sweeper.start()
 
 	return &Container{
 		Config:       config,
 		Logger:       appLogger,
-		EmailService: service,
+		EmailService: mailQueue,
+		MailQueue:    mailQueue,
+		Analytics:    recorder,
+		Owners:       owners,
+		Addresses:    addresses,
+		Verified:     verifiedEmails,
+		URLPolicy:    policy,
 		HashService:  hashService,
 		Storage:      storage,
+		LinkStorage:  linkCache,
+		LinkCache:    linkCache,
+		CodeService:  codeService,
 		Validator:    validator,
+		LinkBuilder:  linkBuilder,
+		RateLimits:   rateLimits,
+		Metrics:      registry,
+		Readiness:    readiness,
+		sweeper:      sweeper,
 	}, nil
 }
+
+// Close releases resources owned by container in dependency order: sweeper
+// stops touching storage first, then mail queue is given [config.MailQueue]
+// DrainTimeout to deliver pending emails and click recorder flushes buffered
+// clicks, then storage connections are closed
+func (c *Container) Close() error {
+	var errs []error
+
+	c.sweeper.stop()
+	c.Logger.Debug("expired records sweeper stopped")
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Config.MailQueue.DrainTimeout)
+	defer cancel()
+	if err := c.MailQueue.Shutdown(ctx); err != nil {
+		c.Logger.Error("mail queue was not drained", "error", err)
+		errs = append(errs, errors.Wrap("mail queue", err))
+	}
+
+	clicksCtx, clicksCancel := context.WithTimeout(context.Background(), c.Config.Analytics.FlushTimeout)
+	defer clicksCancel()
+	if err := c.Analytics.Shutdown(clicksCtx); err != nil {
+		c.Logger.Error("buffered clicks were not flushed", "error", err)
+		errs = append(errs, errors.Wrap("click recorder", err))
+	}
+
+	if closer, ok := c.Storage.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			c.Logger.Error("failed to close storage", "error", err)
+			errs = append(errs, errors.Wrap("storage", err))
+		}
+	}
+
+	c.Logger.Info("container closed")
+
+	return stdErrors.Join(errs...)
+}
+
+// newReadiness registers probes of dependencies service can not work without,
+// mail server is critical only when configured so
+func newReadiness(cfg *config.Config, backend Backend, mailTransport transport.Transport) *health.Readiness {
+	readiness := health.New(cfg.Health.Timeout)
+
+	name := "storage"
+	if cfg.Storage.IsPostgres() {
+		name = "database"
+	}
+	readiness.Register(name, true, health.CheckerFunc(backend.Ping))
+
+	// file and memory sinks have no server to probe
+	if dialer, ok := mailTransport.(transport.Dialer); ok {
+		readiness.Register("mail", cfg.Health.MailCritical, health.Dial(dialer.Address()))
+	}
+
+	return readiness
+}
+
+// registerStats exposes counters kept by components themselves
+func registerStats(registry *metrics.Registry, mailQueue *queue.Queue, recorder *analytics.Recorder, linkCache *cache.Links) {
+	registry.GaugeFunc("link_shortener_mail_queue_depth", "Emails waiting for delivery.", func() float64 {
+		return float64(mailQueue.Stats().Depth)
+	})
+	registry.CounterFunc("link_shortener_emails_dead_lettered_total", "Emails given up after all attempts.", func() float64 {
+		return float64(mailQueue.Stats().DeadLettered)
+	})
+	registry.CounterFunc("link_shortener_clicks_recorded_total", "Redirect clicks saved to storage.", func() float64 {
+		return float64(recorder.Stats().Recorded)
+	})
+	registry.CounterFunc("link_shortener_link_cache_hits_total", "Link lookups served from cache.", func() float64 {
+		return float64(linkCache.Stats().Hits)
+	})
+	registry.CounterFunc("link_shortener_link_cache_misses_total", "Link lookups passed to storage.", func() float64 {
+		return float64(linkCache.Stats().Misses)
+	})
+}
+
+// newStorage picks storage backend by [config.Storage] type
+func newStorage(cfg *config.Config, log logger.Logger) (Backend, error) {
+	switch cfg.Storage.Type {
+	case config.StoragePostgres:
+		log.Info("using postgres storage", "host", cfg.Database.Host, "db", cfg.Database.Name)
+		return pg.New(cfg.Database.PsqlDSN(), cfg.Verification.TTL, log)
+	case config.StorageLocal, "":
+		log.Info("using local storage")
+		return st.New(cfg.Env.String(), st.Options{
+			TTL:   cfg.Verification.TTL,
+			Fsync: cfg.Storage.Fsync,
+		}, log)
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.Storage.Type)
+	}
+}
+
+// newURLPolicy assembles checkers enabled by [config.URLPolicy], further
+// checkers can be added with [urlpolicy.Pipeline.Use]
+func newURLPolicy(cfg config.URLPolicy, log logger.Logger) (*urlpolicy.Pipeline, error) {
+	policy := urlpolicy.New(urlpolicy.Schemes(cfg.Schemes...))
+
+	if cfg.BlockPrivate {
+		var resolver *net.Resolver
+		if cfg.ResolveHosts {
+			resolver = net.DefaultResolver
+		}
+		policy.Use(urlpolicy.PrivateHosts(resolver))
+	}
+
+	if cfg.BlocklistPath != "" {
+		blocklist, err := urlpolicy.NewBlocklist(cfg.BlocklistPath, cfg.BlocklistReload, log)
+		if err != nil {
+			return nil, err
+		}
+		policy.Use(blocklist)
+	}
+
+	return policy, nil
+}
+
+// newAddressChecker assembles recipient screening enabled by [config.EmailPolicy]
+func newAddressChecker(cfg config.EmailPolicy, log logger.Logger) (*address.Checker, error) {
+	opts := address.Options{FoldGmail: cfg.FoldGmail}
+
+	if cfg.DisposablePath != "" {
+		disposable, err := urlpolicy.NewBlocklist(cfg.DisposablePath, cfg.DisposableReload, log)
+		if err != nil {
+			return nil, err
+		}
+		opts.Disposable = disposable
+	}
+
+	if cfg.CheckMX {
+		opts.LookupMX = net.DefaultResolver.LookupMX
+		opts.LookupHost = net.DefaultResolver.LookupHost
+	}
+
+	return address.New(opts), nil
+}
+
+// newHashService picks verification hash issuer by [config.Verification] mode
+func newHashService(cfg config.Verification) (HashService, error) {
+	switch cfg.Mode {
+	case config.VerificationStateless:
+		return security.NewTokenHandler(cfg.Secret, cfg.TTL)
+	case config.VerificationStored, "":
+		return security.NewHashHandler(), nil
+	default:
+		return nil, fmt.Errorf("unknown verification mode: %s", cfg.Mode)
+	}
+}
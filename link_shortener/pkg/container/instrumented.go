@@ -0,0 +1,227 @@
+package container
+
+import (
+	"context"
+	stdErrors "errors"
+	"io"
+	"link_shortener/internal/services/email/queue"
+	"link_shortener/pkg/metrics"
+	"link_shortener/pkg/storage"
+	"time"
+)
+
+// instrumentedStorage observes latency and unexpected errors of storage
+// operations. Not found, conflicts and reached limits are regular outcomes
+// and are not counted as errors
+type instrumentedStorage struct {
+	Backend
+	durations *metrics.HistogramVec
+	errors    *metrics.CounterVec
+}
+
+func newInstrumentedStorage(backend Backend, registry *metrics.Registry) *instrumentedStorage {
+	return &instrumentedStorage{
+		Backend: backend,
+		durations: registry.Histogram("link_shortener_storage_operation_duration_seconds",
+			"Latency of storage operations.", nil, "operation"),
+		errors: registry.Counter("link_shortener_storage_operation_errors_total",
+			"Storage operations failed with unexpected error.", "operation"),
+	}
+}
+
+func (s *instrumentedStorage) observe(operation string, start time.Time, err error) {
+	s.durations.Observe(time.Since(start).Seconds(), operation)
+	if err != nil && !stdErrors.Is(err, storage.ErrNotFound) &&
+		!stdErrors.Is(err, storage.ErrAlreadyExists) && !stdErrors.Is(err, storage.ErrLimitReached) {
+		s.errors.Inc(operation)
+	}
+}
+
+func (s *instrumentedStorage) Save(ctx context.Context, email string, hash string) error {
+	start := time.Now()
+	err := s.Backend.Save(ctx, email, hash)
+	s.observe("save", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Load(ctx context.Context, hash string) (map[string]string, error) {
+	start := time.Now()
+	details, err := s.Backend.Load(ctx, hash)
+	s.observe("load", start, err)
+	return details, err
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, hash string) error {
+	start := time.Now()
+	err := s.Backend.Delete(ctx, hash)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) SaveLink(ctx context.Context, link storage.Link) error {
+	start := time.Now()
+	err := s.Backend.SaveLink(ctx, link)
+	s.observe("save_link", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) LoadLink(ctx context.Context, domain, code string) (storage.Link, error) {
+	start := time.Now()
+	link, err := s.Backend.LoadLink(ctx, domain, code)
+	s.observe("load_link", start, err)
+	return link, err
+}
+
+func (s *instrumentedStorage) UpdateLink(ctx context.Context, link storage.Link) error {
+	start := time.Now()
+	err := s.Backend.UpdateLink(ctx, link)
+	s.observe("update_link", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) CountRedirect(ctx context.Context, domain, code string) error {
+	start := time.Now()
+	err := s.Backend.CountRedirect(ctx, domain, code)
+	s.observe("count_redirect", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) DeleteLink(ctx context.Context, domain, code string) error {
+	start := time.Now()
+	err := s.Backend.DeleteLink(ctx, domain, code)
+	s.observe("delete_link", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) ListLinks(ctx context.Context, owner string) ([]storage.Link, error) {
+	start := time.Now()
+	links, err := s.Backend.ListLinks(ctx, owner)
+	s.observe("list_links", start, err)
+	return links, err
+}
+
+func (s *instrumentedStorage) EachLink(ctx context.Context, owner string, visit func(link storage.Link) error) error {
+	start := time.Now()
+	err := s.Backend.EachLink(ctx, owner, visit)
+	s.observe("each_link", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	start := time.Now()
+	err := s.Backend.SaveClicks(ctx, clicks)
+	s.observe("save_clicks", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) LoadClicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error) {
+	start := time.Now()
+	clicks, err := s.Backend.LoadClicks(ctx, domain, code, from, to)
+	s.observe("load_clicks", start, err)
+	return clicks, err
+}
+
+func (s *instrumentedStorage) SaveEmailIndex(ctx context.Context, index storage.EmailIndex) error {
+	start := time.Now()
+	err := s.Backend.SaveEmailIndex(ctx, index)
+	s.observe("save_email_index", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) LoadEmailIndex(ctx context.Context, email string) (storage.EmailIndex, error) {
+	start := time.Now()
+	index, err := s.Backend.LoadEmailIndex(ctx, email)
+	s.observe("load_email_index", start, err)
+	return index, err
+}
+
+func (s *instrumentedStorage) SaveOwnerKey(ctx context.Context, key storage.OwnerKey) error {
+	start := time.Now()
+	err := s.Backend.SaveOwnerKey(ctx, key)
+	s.observe("save_owner_key", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) LoadOwnerKey(ctx context.Context, hash string) (storage.OwnerKey, error) {
+	start := time.Now()
+	key, err := s.Backend.LoadOwnerKey(ctx, hash)
+	s.observe("load_owner_key", start, err)
+	return key, err
+}
+
+func (s *instrumentedStorage) SaveVerifiedEmail(ctx context.Context, verified storage.VerifiedEmail) error {
+	start := time.Now()
+	err := s.Backend.SaveVerifiedEmail(ctx, verified)
+	s.observe("save_verified_email", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) LoadVerifiedEmail(ctx context.Context, email string) (storage.VerifiedEmail, error) {
+	start := time.Now()
+	verified, err := s.Backend.LoadVerifiedEmail(ctx, email)
+	s.observe("load_verified_email", start, err)
+	return verified, err
+}
+
+func (s *instrumentedStorage) ListVerifiedEmails(ctx context.Context, offset, limit int) ([]storage.VerifiedEmail, error) {
+	start := time.Now()
+	registry, err := s.Backend.ListVerifiedEmails(ctx, offset, limit)
+	s.observe("list_verified_emails", start, err)
+	return registry, err
+}
+
+func (s *instrumentedStorage) RevokeOwnerKeys(ctx context.Context, email, keep string) error {
+	start := time.Now()
+	err := s.Backend.RevokeOwnerKeys(ctx, email, keep)
+	s.observe("revoke_owner_keys", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) PurgeExpired() (int, error) {
+	start := time.Now()
+	purged, err := s.Backend.PurgeExpired()
+	s.observe("purge_expired", start, err)
+	return purged, err
+}
+
+// Close closes wrapped backend when it holds connections
+func (s *instrumentedStorage) Close() error {
+	if closer, ok := s.Backend.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// instrumentedSender counts delivery attempts of emails by kind and result
+type instrumentedSender struct {
+	Service
+	emails *metrics.CounterVec
+}
+
+func newInstrumentedSender(service Service, registry *metrics.Registry) *instrumentedSender {
+	return &instrumentedSender{
+		Service: service,
+		emails: registry.Counter("link_shortener_emails_total",
+			"Email delivery attempts by kind and result.", "kind", "result"),
+	}
+}
+
+func (s *instrumentedSender) SendVerificationEmail(to, verificationLink, acceptLanguage string) error {
+	err := s.Service.SendVerificationEmail(to, verificationLink, acceptLanguage)
+	s.count(queue.KindVerification, err)
+	return err
+}
+
+func (s *instrumentedSender) SendConfirmationEmail(to, acceptLanguage string) error {
+	err := s.Service.SendConfirmationEmail(to, acceptLanguage)
+	s.count(queue.KindConfirmation, err)
+	return err
+}
+
+func (s *instrumentedSender) count(kind string, err error) {
+	result := "sent"
+	if err != nil {
+		result = "failed"
+	}
+	s.emails.Inc(kind, result)
+}
@@ -0,0 +1,80 @@
+package container
+
+import (
+	"context"
+	"link_shortener/pkg/logger"
+	"sync"
+	"time"
+)
+
+type Purger interface {
+	PurgeExpired() (int, error)
+}
+
+// Pinger reports whether storage is usable, see [health.Checker]
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// sweeper periodically purges expired verification records from storage
+type sweeper struct {
+	purger   Purger
+	interval time.Duration
+	logger   logger.Logger
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func newSweeper(purger Purger, interval time.Duration, logger logger.Logger) *sweeper {
+	return &sweeper{
+		purger:   purger,
+		interval: interval,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *sweeper) start() {
+	if s.interval <= 0 {
+		s.logger.Info("expired records sweeper disabled")
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.sweep()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	s.logger.Debug("expired records sweeper started", "interval", s.interval)
+}
+
+func (s *sweeper) sweep() {
+	purged, err := s.purger.PurgeExpired()
+	if err != nil {
+		s.logger.Error("failed to purge expired records", "error", err)
+		return
+	}
+	if purged > 0 {
+		s.logger.Info("expired records purged", "count", purged)
+	}
+}
+
+// stop signals sweeper goroutine to exit and waits for it
+func (s *sweeper) stop() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+}
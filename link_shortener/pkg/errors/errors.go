@@ -39,6 +39,48 @@ var (
 		Status:  http.StatusNotFound,
 	}
 
+	ErrUnauthorized = AppError{
+		Code:    "UNAUTHORIZED",
+		Message: "Authentication required",
+		Status:  http.StatusUnauthorized,
+	}
+
+	ErrForbidden = AppError{
+		Code:    "FORBIDDEN",
+		Message: "Access denied",
+		Status:  http.StatusForbidden,
+	}
+
+	ErrConflict = AppError{
+		Code:    "CONFLICT",
+		Message: "Resource already exists",
+		Status:  http.StatusConflict,
+	}
+
+	ErrURLRejected = AppError{
+		Code:    "URL_REJECTED",
+		Message: "Target URL is not allowed",
+		Status:  http.StatusUnprocessableEntity,
+	}
+
+	ErrLinkGone = AppError{
+		Code:    "LINK_GONE",
+		Message: "Short link is no longer available",
+		Status:  http.StatusGone,
+	}
+
+	ErrVerificationExpired = AppError{
+		Code:    "VERIFICATION_EXPIRED",
+		Message: "Verification link expired",
+		Status:  http.StatusGone,
+	}
+
+	ErrRateLimited = AppError{
+		Code:    "RATE_LIMITED",
+		Message: "Too many requests",
+		Status:  http.StatusTooManyRequests,
+	}
+
 	ErrInternal = AppError{
 		Code:    "INTERNAL_ERROR",
 		Message: "Internal server error",
@@ -82,6 +124,48 @@ func NewNotFoundError(details string) AppError {
 	return err
 }
 
+func NewUnauthorizedError(details string) AppError {
+	err := ErrUnauthorized
+	err.Details = details
+	return err
+}
+
+func NewForbiddenError(details string) AppError {
+	err := ErrForbidden
+	err.Details = details
+	return err
+}
+
+func NewConflictError(details string) AppError {
+	err := ErrConflict
+	err.Details = details
+	return err
+}
+
+func NewURLRejectedError(details string) AppError {
+	err := ErrURLRejected
+	err.Details = details
+	return err
+}
+
+func NewLinkGoneError(details string) AppError {
+	err := ErrLinkGone
+	err.Details = details
+	return err
+}
+
+func NewVerificationExpiredError(details string) AppError {
+	err := ErrVerificationExpired
+	err.Details = details
+	return err
+}
+
+func NewRateLimitError(details string) AppError {
+	err := ErrRateLimited
+	err.Details = details
+	return err
+}
+
 func _(details string) AppError {
 	err := ErrInternal
 	err.Details = details
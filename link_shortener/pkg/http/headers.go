@@ -0,0 +1,7 @@
+package http
+
+const (
+	RequestIDHeader  = "X-Request-ID"
+	RequestIPHeader  = "X-Forwarded-For"
+	RetryAfterHeader = "Retry-After"
+)
@@ -0,0 +1,73 @@
+package linkbuilder
+
+import (
+	"fmt"
+	"link_shortener/internal/config"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Builder produces absolute public URLs for links handed out to clients,
+// so verification and short links always agree on the public origin
+type Builder struct {
+	base *url.URL
+}
+
+// New derives public origin from [config.HttpServer]: ExternalURL when set
+// (reverse proxy setups), otherwise Address, otherwise Schema://Host:Port.
+// PathPrefix is appended to the origin path, routes themselves are not affected
+func New(cfg config.HttpServer) (*Builder, error) {
+	const fn = "pkg.linkbuilder.New"
+
+	raw := cfg.ExternalURL
+	if raw == "" {
+		raw = cfg.Address
+	}
+	if raw == "" {
+		raw = fmt.Sprintf("%s://%s", cfg.Schema, hostPort(cfg.Schema, cfg.Host, cfg.Port))
+	}
+
+	base, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("%s: public url %q must contain scheme and host", fn, raw)
+	}
+
+	base.Path = path.Join("/", base.Path, cfg.PathPrefix)
+	base.RawQuery = ""
+	base.Fragment = ""
+
+	return &Builder{base: base}, nil
+}
+
+// Build joins path elements to public origin
+func (b *Builder) Build(elems ...string) string {
+	return b.BuildFor("", elems...)
+}
+
+// BuildFor joins path elements to public origin served under vanity host,
+// scheme and path prefix are kept
+func (b *Builder) BuildFor(host string, elems ...string) string {
+	u := *b.base
+	if host != "" {
+		u.Host = host
+	}
+	u.Path = path.Join(append([]string{b.base.Path}, elems...)...)
+	return u.String()
+}
+
+// Base returns public origin including path prefix
+func (b *Builder) Base() string {
+	return strings.TrimSuffix(b.base.String(), "/")
+}
+
+// hostPort omits port when it is default for schema
+func hostPort(schema, host, port string) string {
+	if port == "" || (schema == "http" && port == "80") || (schema == "https" && port == "443") {
+		return host
+	}
+	return host + ":" + port
+}
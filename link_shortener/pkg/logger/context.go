@@ -0,0 +1,20 @@
+package logger
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying logger, usually scoped to single request
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns logger carried by ctx or fallback when there is none
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(Logger); ok {
+			return logger
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"link_shortener/pkg/errors"
+	"net/http"
+	"strings"
+)
+
+// BearerToken admits only requests carrying token in Authorization header,
+// others are rejected with 401. Tokens are compared in constant time
+func BearerToken(token string, writer ErrorWriter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(given)), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writer.WriteError(w, errors.NewUnauthorizedError("Valid bearer token required"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"link_shortener/pkg/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatchedRoute labels requests no route matched, keeping arbitrary
+// paths out of metric labels
+const unmatchedRoute = "unmatched"
+
+// Metrics counts requests and observes their latency by method, route
+// pattern and status. It has to wrap [http.ServeMux] directly, route
+// pattern is known only after mux matched request
+func Metrics(registry *metrics.Registry) Middleware {
+	requests := registry.Counter("link_shortener_http_requests_total",
+		"HTTP requests served by route and status.", "method", "route", "status")
+	durations := registry.Histogram("link_shortener_http_request_duration_seconds",
+		"Latency of HTTP requests by route.", nil, "method", "route")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			writer := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(writer, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+			requests.Inc(r.Method, route, strconv.Itoa(writer.status))
+			durations.Observe(time.Since(start).Seconds(), r.Method, route)
+		})
+	}
+}
+
+// statusWriter records status of response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets [http.ResponseController] and writer lookups reach wrapped writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"link_shortener/pkg/errors"
+	pkgHttp "link_shortener/pkg/http"
+	"link_shortener/pkg/ratelimit"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxKeyBodySize caps request body read to extract rate limit key
+const maxKeyBodySize = 1 << 20
+
+type ErrorWriter interface {
+	WriteError(w http.ResponseWriter, err error)
+}
+
+// KeyFunc extracts rate limit key from request, empty key skips limiting
+type KeyFunc func(r *http.Request) string
+
+// RateLimitMiddleware rejects requests exceeding limit for key with 429 and
+// Retry-After header. Keys of different limiters share store, so name is
+// used as key namespace
+func RateLimitMiddleware(name string, store ratelimit.Store, limit ratelimit.Limit,
+	key KeyFunc, writer ErrorWriter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, retryAfter := store.Take(name+":"+k, limit, time.Now())
+			if !allowed {
+				seconds := int(retryAfter.Round(time.Second) / time.Second)
+				if seconds < 1 {
+					seconds = 1
+				}
+				w.Header().Set(pkgHttp.RetryAfterHeader, strconv.Itoa(seconds))
+				writer.WriteError(w, errors.NewRateLimitError("Too many requests by "+name))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIPKey keys requests by client IP, X-Forwarded-For is only honoured
+// when service runs behind trusted proxy
+func ClientIPKey(trustProxy bool) KeyFunc {
+	return func(r *http.Request) string {
+		return ClientIP(r, trustProxy)
+	}
+}
+
+// ClientIP returns address of client. Behind trusted proxy it is the rightmost
+// X-Forwarded-For entry, the one appended by the proxy itself, entries left of
+// it come from the client and may be forged
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		forwarded := r.Header.Values(pkgHttp.RequestIPHeader)
+		if len(forwarded) > 0 {
+			last := forwarded[len(forwarded)-1]
+			if i := strings.LastIndex(last, ","); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := net.ParseIP(strings.TrimSpace(last)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// JSONEmailKey keys requests by normalized "email" field of JSON body.
+// Body is restored so handlers can read it again
+func JSONEmailKey(normalize func(string) string) KeyFunc {
+	return func(r *http.Request) string {
+		if r.Body == nil {
+			return ""
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxKeyBodySize))
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		var payload struct {
+			Email string `json:"email"`
+		}
+		if err = json.Unmarshal(body, &payload); err != nil || payload.Email == "" {
+			return ""
+		}
+		return normalize(payload.Email)
+	}
+}
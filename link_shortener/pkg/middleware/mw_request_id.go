@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	pkgHttp "link_shortener/pkg/http"
+	"link_shortener/pkg/logger"
+	"net/http"
+	"time"
+)
+
+// maxRequestIDLength bounds request ID accepted from client
+const maxRequestIDLength = 64
+
+type requestIDKey struct{}
+
+// RequestID tags every request with ID taken from X-Request-ID header or
+// generated, echoes it in response and puts logger scoped with request_id,
+// method and path into request context, see [logger.FromContext]. Completed
+// requests are logged with status and duration
+func RequestID(log logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(pkgHttp.RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(pkgHttp.RequestIDHeader, id)
+
+			scoped := log.With("request_id", id, "method", r.Method, "path", r.URL.Path)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = logger.NewContext(ctx, scoped)
+
+			writer := &scopedWriter{statusWriter: statusWriter{ResponseWriter: w, status: http.StatusOK}, logger: scoped}
+			next.ServeHTTP(writer, r.WithContext(ctx))
+
+			scoped.Debug("request completed", "status", writer.status,
+				"duration", time.Since(start).String())
+		})
+	}
+}
+
+// RequestIDFromContext returns ID assigned by [RequestID], empty outside of it
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// scopedWriter records response status and exposes request logger to code
+// which gets only writer, like error writers
+type scopedWriter struct {
+	statusWriter
+	logger logger.Logger
+}
+
+func (w *scopedWriter) Logger() logger.Logger {
+	return w.logger
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// validRequestID accepts IDs of proxies and clients made of safe characters only,
+// anything else would let client inject arbitrary text into logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+const (
+	DefaultSize   = 256
+	MinSize       = 64
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 16
+)
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// Options of rendered code. Size is image side in pixels, Margin is quiet
+// zone in modules and Level is error correction level L, M, Q or H
+type Options struct {
+	Size   int
+	Margin int
+	Level  string
+}
+
+// DefaultOptions returns medium error correction 256px code with standard margin
+func DefaultOptions() Options {
+	return Options{
+		Size:   DefaultSize,
+		Margin: DefaultMargin,
+		Level:  "M",
+	}
+}
+
+// Validate reports options out of supported ranges
+func (o Options) Validate() error {
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	}
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+	}
+	if _, ok := levels[strings.ToUpper(o.Level)]; !ok {
+		return fmt.Errorf("level must be one of L, M, Q, H")
+	}
+	return nil
+}
+
+// Render encodes content as QR code image in format
+func Render(content string, format Format, opts Options) ([]byte, error) {
+	const fn = "pkg.qr.Render"
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	code, err := qrcode.New(content, levels[strings.ToUpper(opts.Level)])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	// quiet zone is drawn by layout, library one has fixed width
+	code.DisableBorder = true
+
+	l := newLayout(code.Bitmap(), opts)
+
+	switch format {
+	case PNG:
+		return l.png()
+	case SVG:
+		return l.svg(), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q", fn, format)
+	}
+}
+
+// ContentType returns MIME type of format
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// layout places modules into image of requested size: module side is the
+// largest integer fitting modules with margin, leftover pixels are split
+// evenly around the code
+type layout struct {
+	bitmap [][]bool
+	size   int
+	module int
+	offset int
+}
+
+func newLayout(bitmap [][]bool, opts Options) layout {
+	modules := len(bitmap) + 2*opts.Margin
+	module := max(opts.Size/modules, 1)
+	size := max(opts.Size, module*modules)
+	offset := (size - module*len(bitmap)) / 2
+
+	return layout{
+		bitmap: bitmap,
+		size:   size,
+		module: module,
+		offset: offset,
+	}
+}
+
+func (l layout) png() ([]byte, error) {
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, l.size, l.size), palette)
+
+	for y, row := range l.bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			x0, y0 := l.offset+x*l.module, l.offset+y*l.module
+			for py := y0; py < y0+l.module; py++ {
+				for px := x0; px < x0+l.module; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// svg draws dark modules as single path, one rectangle per horizontal run
+func (l layout) svg() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		l.size, l.size, l.size, l.size)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, l.size, l.size)
+
+	for y, row := range l.bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv%dh-%dz",
+				l.offset+start*l.module, l.offset+y*l.module,
+				(x-start)*l.module, l.module, (x-start)*l.module)
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Period, refilled evenly, with burst of Requests
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Store keeps token buckets by key. MemoryStore serves single instance,
+// shared implementations (e.g. redis) can be plugged in for replicas
+type Store interface {
+	// Take consumes one token from bucket of key and reports whether request
+	// is allowed, when it is not also returns time until next token
+	Take(key string, limit Limit, now time.Time) (bool, time.Duration)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	idle      time.Duration
+}
+
+// NewMemoryStore returns in-memory store dropping buckets untouched for idle,
+// idle should not be shorter than the longest limit period
+func NewMemoryStore(idle time.Duration) *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		idle:    idle,
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		return true, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*limit.rate())
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / limit.rate()
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// sweep drops idle buckets at most once per idle interval, callers hold mu
+func (s *MemoryStore) sweep(now time.Time) {
+	if s.idle <= 0 || now.Sub(s.lastSweep) < s.idle {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= s.idle {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,25 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// apiKeyBytes is entropy of issued API keys
+const apiKeyBytes = 32
+
+// NewAPIKey returns random URL-safe API key
+func NewAPIKey() (string, error) {
+	buf := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("pkg.security.NewAPIKey: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashAPIKey returns hex sha256 of key, keys are stored and looked up by it
+func HashAPIKey(key string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
@@ -0,0 +1,36 @@
+package security
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const (
+	codeAlphabet      = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	DefaultCodeLength = 7
+)
+
+type Code struct {
+	length int
+}
+
+// NewCodeGenerator returns generator of random base62 short codes of given length
+func NewCodeGenerator(length int) *Code {
+	if length <= 0 {
+		length = DefaultCodeLength
+	}
+	return &Code{length: length}
+}
+
+func (c Code) Generate() (string, error) {
+	max := big.NewInt(int64(len(codeAlphabet)))
+	code := make([]byte, c.length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,24 @@
+package security
+
+import (
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordBytes is the longest password bcrypt hashes, it counts
+// bytes, so multibyte passwords hit it with fewer characters
+const MaxPasswordBytes = 72
+
+// HashPassword returns bcrypt hash of link password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("pkg.security.HashPassword: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash made by [HashPassword]
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
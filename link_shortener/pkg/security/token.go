@@ -0,0 +1,91 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"link_shortener/pkg/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSecretLength guards against trivially brute-forceable signing keys
+const minSecretLength = 32
+
+var ErrInvalidToken = errors.New("invalid verification token")
+
+// Token issues HMAC-SHA256 signed verification tokens carrying email and
+// expiry, so they can be checked without storage lookup. Token payload is
+// only encoded, not encrypted, and stays valid until expiry
+type Token struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewTokenHandler returns token issuer, ttl must be positive as tokens
+// are never consumed and expiry is the only limit of their use
+func NewTokenHandler(secret string, ttl time.Duration) (*Token, error) {
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("token secret must be at least %d bytes", minSecretLength)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("token ttl must be positive, got %s", ttl)
+	}
+	return &Token{
+		secret: []byte(secret),
+		ttl:    ttl,
+	}, nil
+}
+
+// GetHash returns signed token for email in form base64(email|expiry).base64(signature)
+func (t Token) GetHash(email string) string {
+	expiresAt := time.Now().Add(t.ttl).Unix()
+	payload := fmt.Sprintf("%s|%d", email, expiresAt)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return encoded + "." + t.sign(encoded)
+}
+
+// Verify checks token signature and returns record details with the same
+// keys as storage Load. Expiry is reported in details and not enforced here
+func (t Token) Verify(token string) (map[string]string, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(t.sign(encoded))) {
+		return nil, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := string(raw)
+	sep := strings.LastIndex(payload, "|")
+	if sep <= 0 {
+		return nil, ErrInvalidToken
+	}
+
+	// tokens without expiry are refused, they could be replayed forever
+	expiresAt, err := strconv.ParseInt(payload[sep+1:], 10, 64)
+	if err != nil || expiresAt <= 0 {
+		return nil, ErrInvalidToken
+	}
+
+	details := map[string]string{
+		storage.KeyEmail:     payload[:sep],
+		storage.KeyHash:      token,
+		storage.KeyCreatedAt: "",
+		storage.KeyExpiresAt: storage.FormatTime(time.Unix(expiresAt, 0)),
+	}
+
+	return details, nil
+}
+
+func (t Token) sign(encoded string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type LinkStorage interface {
+	SaveLink(ctx context.Context, link storage.Link) error
+	LoadLink(ctx context.Context, domain, code string) (storage.Link, error)
+	UpdateLink(ctx context.Context, link storage.Link) error
+	CountRedirect(ctx context.Context, domain, code string) error
+	DeleteLink(ctx context.Context, domain, code string) error
+	ListLinks(ctx context.Context, owner string) ([]storage.Link, error)
+	EachLink(ctx context.Context, owner string, visit func(link storage.Link) error) error
+}
+
+type Options struct {
+	// Size bounds number of cached lookups, zero disables caching
+	Size int
+	// TTL bounds how long changes made by other instances stay unseen
+	TTL time.Duration
+	// NegativeTTL is how long missing links are remembered
+	NegativeTTL time.Duration
+}
+
+// Stats is a snapshot of cache counters
+type Stats struct {
+	Enabled   bool  `json:"enabled"`
+	Size      int   `json:"size"`
+	Capacity  int   `json:"capacity"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Negative  int64 `json:"negative_hits"`
+	Evictions int64 `json:"evictions"`
+}
+
+type key struct {
+	domain string
+	code   string
+}
+
+// lookup is cached result of LoadLink, found is false for negative entries
+type lookup struct {
+	link  storage.Link
+	found bool
+}
+
+// Links decorates link storage with in-memory LRU of LoadLink results.
+// Writes going through it invalidate cached entries, so staleness is
+// bounded by TTL only for writes made by other instances or storage itself
+type Links struct {
+	LinkStorage
+	opts Options
+	log  logger.Logger
+
+	mu      sync.Mutex
+	entries *lru[key, lookup]
+	// generation changes on every invalidation, lookups loaded across it
+	// may be stale and are not cached
+	generation uint64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	negative  atomic.Int64
+	evictions atomic.Int64
+}
+
+func New(links LinkStorage, opts Options, log logger.Logger) *Links {
+	if opts.Size < 0 {
+		opts.Size = 0
+	}
+	return &Links{
+		LinkStorage: links,
+		opts:        opts,
+		log:         log,
+		entries:     newLRU[key, lookup](opts.Size),
+	}
+}
+
+func (c *Links) LoadLink(ctx context.Context, domain, code string) (storage.Link, error) {
+	const fn = "pkg.storage.cache.links.LoadLink"
+	if !c.enabled() {
+		return c.LinkStorage.LoadLink(ctx, domain, code)
+	}
+
+	k := key{domain: domain, code: code}
+	c.mu.Lock()
+	cached, ok := c.entries.get(k, time.Now())
+	generation := c.generation
+	c.mu.Unlock()
+	if ok {
+		c.hits.Add(1)
+		if !cached.found {
+			c.negative.Add(1)
+			return storage.Link{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		return cached.link, nil
+	}
+	c.misses.Add(1)
+
+	link, err := c.LinkStorage.LoadLink(ctx, domain, code)
+	switch {
+	case err == nil:
+		c.add(k, lookup{link: link, found: true}, generation, c.opts.TTL)
+	case errors.Is(err, storage.ErrNotFound):
+		c.add(k, lookup{}, generation, c.opts.NegativeTTL)
+	}
+	return link, err
+}
+
+func (c *Links) SaveLink(ctx context.Context, link storage.Link) error {
+	err := c.LinkStorage.SaveLink(ctx, link)
+	c.invalidate(ctx, link.Domain, link.Code)
+	return err
+}
+
+func (c *Links) UpdateLink(ctx context.Context, link storage.Link) error {
+	err := c.LinkStorage.UpdateLink(ctx, link)
+	c.invalidate(ctx, link.Domain, link.Code)
+	return err
+}
+
+func (c *Links) DeleteLink(ctx context.Context, domain, code string) error {
+	err := c.LinkStorage.DeleteLink(ctx, domain, code)
+	c.invalidate(ctx, domain, code)
+	return err
+}
+
+// CountRedirect keeps cached click counter in step with storage so capped
+// links stay cached while they are redirected
+func (c *Links) CountRedirect(ctx context.Context, domain, code string) error {
+	err := c.LinkStorage.CountRedirect(ctx, domain, code)
+	if !c.enabled() {
+		return err
+	}
+
+	if err != nil {
+		c.invalidate(ctx, domain, code)
+		return err
+	}
+
+	c.mu.Lock()
+	c.entries.update(key{domain: domain, code: code}, func(cached lookup) lookup {
+		cached.link.Clicks++
+		return cached
+	})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Links) Stats() Stats {
+	c.mu.Lock()
+	size := c.entries.len()
+	c.mu.Unlock()
+
+	return Stats{
+		Enabled:   c.enabled(),
+		Size:      size,
+		Capacity:  c.opts.Size,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Negative:  c.negative.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+func (c *Links) enabled() bool {
+	return c.opts.Size > 0
+}
+
+func (c *Links) add(k key, value lookup, generation uint64, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	if generation != c.generation {
+		c.mu.Unlock()
+		return
+	}
+	evicted := c.entries.add(k, value, time.Now(), ttl)
+	c.mu.Unlock()
+
+	if evicted {
+		c.evictions.Add(1)
+	}
+}
+
+func (c *Links) invalidate(ctx context.Context, domain, code string) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries.remove(key{domain: domain, code: code})
+	c.generation++
+	c.mu.Unlock()
+
+	logger.FromContext(ctx, c.log).Debug("link cache entry invalidated", "domain", domain, "code", code)
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// lru is bounded least recently used map whose entries also expire,
+// it is not safe for concurrent use
+type lru[K comparable, V any] struct {
+	capacity int
+	order    *list.List
+	items    map[K]*list.Element
+}
+
+func newLRU[K comparable, V any](capacity int) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// get returns live value of key and marks it recently used, expired
+// entries are dropped
+func (c *lru[K, V]) get(key K, now time.Time) (V, bool) {
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	item := element.Value.(*entry[K, V])
+	if !now.Before(item.expiresAt) {
+		c.removeElement(element)
+		return zero, false
+	}
+
+	c.order.MoveToFront(element)
+	return item.value, true
+}
+
+// add stores value until now+ttl and reports whether least recently used
+// entry was evicted to make room
+func (c *lru[K, V]) add(key K, value V, now time.Time, ttl time.Duration) bool {
+	if element, ok := c.items[key]; ok {
+		item := element.Value.(*entry[K, V])
+		item.value = value
+		item.expiresAt = now.Add(ttl)
+		c.order.MoveToFront(element)
+		return false
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: now.Add(ttl)})
+	if c.order.Len() <= c.capacity {
+		return false
+	}
+	c.removeElement(c.order.Back())
+	return true
+}
+
+// update changes value of live key in place keeping its expiry and position
+func (c *lru[K, V]) update(key K, change func(V) V) {
+	if element, ok := c.items[key]; ok {
+		item := element.Value.(*entry[K, V])
+		item.value = change(item.value)
+	}
+}
+
+func (c *lru[K, V]) remove(key K) {
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+func (c *lru[K, V]) len() int {
+	return c.order.Len()
+}
+
+func (c *lru[K, V]) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry[K, V]).key)
+}
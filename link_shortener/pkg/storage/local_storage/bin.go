@@ -1,15 +1,30 @@
 package local_storage
 
-import "strings"
+import (
+	"time"
+)
 
 type Bin struct {
-	Email string `json:"email" validator:"required,email"`
-	Hash  string `json:"hash" validator:"required"`
+	Email     string    `json:"email" validator:"required,email"`
+	Hash      string    `json:"hash" validator:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
 }
 
-func newBin(email string, hash string) *Bin {
-	return &Bin{
-		Email: strings.ToLower(email),
-		Hash:  hash,
+func newBin(email string, hash string, ttl time.Duration) *Bin {
+	now := time.Now().UTC()
+	bin := &Bin{
+		Email:     email,
+		Hash:      hash,
+		CreatedAt: now,
 	}
+	if ttl > 0 {
+		bin.ExpiresAt = now.Add(ttl)
+	}
+	return bin
+}
+
+// expired reports whether bin has expiry set and it has already passed
+func (b *Bin) expired(now time.Time) bool {
+	return !b.ExpiresAt.IsZero() && now.After(b.ExpiresAt)
 }
@@ -0,0 +1,148 @@
+package local_storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SaveClicks appends click events to per link JSON Lines files
+func (s *Storage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	const fn = "pkg.storage.local_storage.clicks.SaveClicks"
+	log := logger.FromContext(ctx, s.Log)
+	type linkKey struct{ domain, code string }
+	byLink := make(map[linkKey][]storage.Click)
+	for _, click := range clicks {
+		key := linkKey{domain: click.Domain, code: click.Code}
+		byLink[key] = append(byLink[key], click)
+	}
+
+	var errs []error
+	for key, events := range byLink {
+		if err := s.appendClicks(key.domain, key.code, events); err != nil {
+			log.Error(fmt.Sprintf("%s: %s", fn, err.Error()), "domain", key.domain, "code", key.code)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %w", fn, errors.Join(errs...))
+	}
+
+	log.Debug("clicks saved to local storage", "count", len(clicks))
+
+	return nil
+}
+
+func (s *Storage) appendClicks(domain, code string, clicks []storage.Click) error {
+	fileName, err := clicksName(domain, code)
+	if err != nil {
+		return err
+	}
+
+	if err = s.FileHandler.mkdir(filepath.Dir(fileName)); err != nil {
+		return err
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	file, err := os.OpenFile(filepath.Join(s.FileHandler.WorkDir, fileName),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, click := range clicks {
+		if err = enc.Encode(click); err != nil {
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if s.FileHandler.Fsync {
+		return file.Sync()
+	}
+	return nil
+}
+
+// LoadClicks streams click events of link registered within [from, to)
+func (s *Storage) LoadClicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error) {
+	const fn = "pkg.storage.local_storage.clicks.LoadClicks"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := clicksName(domain, code)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	file, err := os.Open(filepath.Join(s.FileHandler.WorkDir, fileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer file.Close()
+
+	var clicks []storage.Click
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var click storage.Click
+		if err = json.Unmarshal(scanner.Bytes(), &click); err != nil {
+			log.Warn(fmt.Sprintf("%s: skipping malformed click", fn), "code", code, "error", err)
+			continue
+		}
+		if click.Time.Before(from) || !click.Time.Before(to) {
+			continue
+		}
+		clicks = append(clicks, click)
+	}
+	if err = scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return clicks, nil
+}
+
+// deleteClicks removes click history of link, missing history is fine
+func (s *Storage) deleteClicks(domain, code string) error {
+	fileName, err := clicksName(domain, code)
+	if err != nil {
+		return err
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	if !s.fileExists(fileName) {
+		return nil
+	}
+	return s.FileHandler.delete(fileName)
+}
+
+// clicksName maps short code to JSON Lines file inside [CLICKSDIR]
+func clicksName(domain, code string) (string, error) {
+	dir, err := scopeDir(CLICKSDIR, domain)
+	if err != nil {
+		return "", err
+	}
+	if err = checkCode(code); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, code+".jsonl"), nil
+}
@@ -0,0 +1,80 @@
+package local_storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"os"
+	"path/filepath"
+)
+
+// SaveEmailIndex creates or replaces index entry of email
+func (s *Storage) SaveEmailIndex(ctx context.Context, index storage.EmailIndex) error {
+	const fn = "pkg.storage.local_storage.emails.SaveEmailIndex"
+	log := logger.FromContext(ctx, s.Log)
+	fileName := emailName(EMAILSDIR, index.Email)
+
+	payload, err := json.Marshal(index)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	if err = s.FileHandler.write(fileName, payload, false); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("email index saved to local storage")
+
+	return nil
+}
+
+func (s *Storage) LoadEmailIndex(ctx context.Context, email string) (storage.EmailIndex, error) {
+	const fn = "pkg.storage.local_storage.emails.LoadEmailIndex"
+	log := logger.FromContext(ctx, s.Log)
+	fileName := emailName(EMAILSDIR, email)
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	file, err := os.Open(filepath.Join(s.FileHandler.WorkDir, fileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return storage.EmailIndex{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.EmailIndex{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.EmailIndex{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var index storage.EmailIndex
+	if err = json.Unmarshal(payload, &index); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.EmailIndex{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return index, nil
+}
+
+// emailName maps email to file inside dir, email is hashed so file
+// name is safe and does not disclose the address
+func emailName(dir, email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
@@ -1,6 +1,7 @@
 package local_storage
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"link_shortener/pkg/logger"
@@ -10,16 +11,32 @@ import (
 	"runtime"
 )
 
-const TMPDIR = "tmp"
+const (
+	TMPDIR = "tmp"
+	// APPDIR is dedicated directory of prod records inside system temp dir,
+	// which is shared with other processes and must never be swept itself
+	APPDIR      = "link_shortener"
+	LINKSDIR    = "links"
+	CLICKSDIR   = "clicks"
+	KEYSDIR     = "keys"
+	EMAILSDIR   = "emails"
+	VERIFIEDDIR = "verified"
+	// tempSuffix marks files being written, leftovers are removed on startup
+	tempSuffix = ".tmp"
+)
 
 type Handler struct {
 	WorkDir string
 	Log     logger.Logger
+	// Fsync flushes written files and their directory to disk before
+	// write is reported as done
+	Fsync bool
 }
 
-func newHandler(env string, logger logger.Logger) (*Handler, error) {
+func newHandler(env string, fsync bool, logger logger.Logger) (*Handler, error) {
 	fh := &Handler{
-		Log: logger,
+		Log:   logger,
+		Fsync: fsync,
 	}
 
 	path, err := getFullPath(env, logger)
@@ -30,6 +47,15 @@ func newHandler(env string, logger logger.Logger) (*Handler, error) {
 
 	fh.WorkDir = path
 
+	for _, dir := range []string{LINKSDIR, CLICKSDIR, KEYSDIR, EMAILSDIR, VERIFIEDDIR} {
+		if err = os.MkdirAll(filepath.Join(path, dir), 0755); err != nil {
+			logger.Error(err.Error())
+			return nil, fmt.Errorf("%s: %w", utils.GetContext(), err)
+		}
+	}
+
+	fh.removeOrphans()
+
 	logger.Debug("fileHandler initialized")
 
 	return fh, nil
@@ -37,13 +63,11 @@ func newHandler(env string, logger logger.Logger) (*Handler, error) {
 
 func (h *Handler) load(name string) (io.ReadCloser, error) {
 	const fn = "pkg.storage.local_storage.file_handler.load"
-	h.Log.With(fn)
-
 	filePath := filepath.Join(h.WorkDir, name)
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		h.Log.Error(err.Error())
+		h.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
@@ -52,27 +76,78 @@ func (h *Handler) load(name string) (io.ReadCloser, error) {
 	return file, nil
 }
 
-func (h *Handler) save(name string) (io.WriteCloser, error) {
-	const fn = "pkg.storage.local_storage.file_handler.save"
-	h.Log.With(fn)
+// write stores payload under name atomically: data goes to temp file in the
+// same directory which is then moved in place, so readers never observe
+// partially written file. With exclusive set existing file is never replaced
+// and [os.ErrExist] is returned instead
+func (h *Handler) write(name string, payload []byte, exclusive bool) error {
+	const fn = "pkg.storage.local_storage.file_handler.write"
 	filePath := filepath.Join(h.WorkDir, name)
-	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	dir := filepath.Dir(filePath)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".*"+tempSuffix)
 	if err != nil {
 		h.Log.Error(err.Error())
-		return nil, fmt.Errorf("%s: %w", fn, err)
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err = tmp.Write(payload); err == nil && h.Fsync {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if exclusive {
+		// link fails if target exists, which rename would silently replace
+		err = os.Link(tmpPath, filePath)
+	} else {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			h.Log.Error(err.Error())
+		}
+		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	h.Log.Debug("file opened for writing")
+	if h.Fsync {
+		if err = syncDir(dir); err != nil {
+			h.Log.Warn("failed to sync directory", "dir", dir, "error", err)
+		}
+	}
 
-	return file, nil
+	h.Log.Debug("file written atomically")
+
+	return nil
+}
+
+// probe checks that work directory accepts new files, probe file name
+// matches temp files so leftovers are removed on startup
+func (h *Handler) probe() error {
+	file, err := os.CreateTemp(h.WorkDir, ".probe.json.*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	name := file.Name()
+	if err = file.Close(); err != nil {
+		_ = os.Remove(name)
+		return err
+	}
+	return os.Remove(name)
 }
 
 func (h *Handler) delete(name string) error {
 	const fn = "pkg.storage.local_storage.file_handler.delete"
-	h.Log.With(fn)
 	file := filepath.Join(h.WorkDir, name)
 	if err := os.Remove(file); err != nil {
-		h.Log.Error(err.Error())
+		h.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return fmt.Errorf("%s: %w", fn, err)
 	}
 
@@ -81,10 +156,59 @@ func (h *Handler) delete(name string) error {
 	return nil
 }
 
+// removeOrphans removes temp files left by writes interrupted by process crash
+func (h *Handler) removeOrphans() {
+	dirs := []string{h.WorkDir}
+	for _, flat := range []string{KEYSDIR, EMAILSDIR, VERIFIEDDIR} {
+		dirs = append(dirs, filepath.Join(h.WorkDir, flat))
+	}
+	for _, base := range []string{LINKSDIR, CLICKSDIR} {
+		dir := filepath.Join(h.WorkDir, base)
+		dirs = append(dirs, dir)
+		// vanity domain subdirectories
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		orphans, err := filepath.Glob(filepath.Join(dir, ".*.json.*"+tempSuffix))
+		if err != nil {
+			h.Log.Warn("failed to list orphaned temp files", "dir", dir, "error", err)
+			continue
+		}
+		for _, orphan := range orphans {
+			if err = os.Remove(orphan); err != nil {
+				h.Log.Warn("failed to remove orphaned temp file", "file", orphan, "error", err)
+				continue
+			}
+			h.Log.Info("orphaned temp file removed", "file", orphan)
+		}
+	}
+}
+
+// mkdir creates directory inside work dir if it does not exist yet
+func (h *Handler) mkdir(name string) error {
+	return os.MkdirAll(filepath.Join(h.WorkDir, name), 0755)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
 func getFullPath(env string, log logger.Logger) (string, error) {
 	const fn = "pkg.storage.local_storage.file_handler.getFullPath"
-	log.With(fn)
-
 	switch env {
 	case "dev":
 		_, filename, _, _ := runtime.Caller(0)
@@ -92,14 +216,19 @@ func getFullPath(env string, log logger.Logger) (string, error) {
 		path := filepath.Join(currentDir, TMPDIR)
 		err := os.MkdirAll(path, 0755)
 		if err != nil {
-			log.Error(err.Error())
+			log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 			return "", fmt.Errorf("%s: %w", fn, err)
 		}
 		return path, nil
 	case "prod":
-		return os.TempDir(), nil
+		path := filepath.Join(os.TempDir(), APPDIR)
+		if err := os.MkdirAll(path, 0700); err != nil {
+			log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+			return "", fmt.Errorf("%s: %w", fn, err)
+		}
+		return path, nil
 	default:
-		log.Error(fmt.Sprintf("unknown env type: %s", env))
+		log.Error(fmt.Sprintf("%s: unknown env type: %s", fn, env))
 		return "", fmt.Errorf("%s: %w", fn, fmt.Errorf(
 			"unknown env type: %s", env))
 	}
@@ -0,0 +1,367 @@
+package local_storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Storage) SaveLink(ctx context.Context, link storage.Link) error {
+	const fn = "pkg.storage.local_storage.links.SaveLink"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := linkName(link.Domain, link.Code)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = s.FileHandler.mkdir(filepath.Dir(fileName)); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	payload, err := json.Marshal(link)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = s.FileHandler.write(fileName, payload, true); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			log.Warn(fmt.Sprintf("%s:%s already exists", fn, fileName))
+			return fmt.Errorf("%s: %w", fn, storage.ErrAlreadyExists)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("link saved to local storage", "code", link.Code)
+
+	return nil
+}
+
+func (s *Storage) LoadLink(ctx context.Context, domain, code string) (storage.Link, error) {
+	const fn = "pkg.storage.local_storage.links.LoadLink"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := linkName(domain, code)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if !s.fileExists(fileName) {
+		return storage.Link{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+	}
+
+	link, err := s.readLink(fileName)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.Link{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return link, nil
+}
+
+// UpdateLink replaces stored link, link must already exist. Redirect
+// counter is owned by storage and kept as is
+func (s *Storage) UpdateLink(ctx context.Context, link storage.Link) error {
+	const fn = "pkg.storage.local_storage.links.UpdateLink"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := linkName(link.Domain, link.Code)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	current, err := s.readLink(fileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	link.Clicks = current.Clicks
+
+	payload, err := json.Marshal(link)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = s.FileHandler.write(fileName, payload, false); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("link updated in local storage", "code", link.Code)
+
+	return nil
+}
+
+// CountRedirect increments redirect counter of link unless it reached
+// MaxClicks, in which case [storage.ErrLimitReached] is returned
+func (s *Storage) CountRedirect(ctx context.Context, domain, code string) error {
+	const fn = "pkg.storage.local_storage.links.CountRedirect"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := linkName(domain, code)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	link, err := s.readLink(fileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if link.Exhausted() {
+		return fmt.Errorf("%s: %w", fn, storage.ErrLimitReached)
+	}
+	link.Clicks++
+
+	payload, err := json.Marshal(link)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = s.FileHandler.write(fileName, payload, false); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+// ListLinks returns links created by owner, newest first. Local storage
+// keeps no owner index, so every link file is read
+func (s *Storage) ListLinks(ctx context.Context, owner string) ([]storage.Link, error) {
+	const fn = "pkg.storage.local_storage.links.ListLinks"
+	log := logger.FromContext(ctx, s.Log)
+	var links []storage.Link
+
+	err := s.walkLinks(func(_ string, link storage.Link) error {
+		if link.Owner == owner {
+			links = append(links, link)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].CreatedAt.After(links[j].CreatedAt)
+	})
+
+	return links, nil
+}
+
+// EachLink calls visit for every link of owner without loading them all
+// at once, iteration stops at first error returned by visit
+func (s *Storage) EachLink(ctx context.Context, owner string, visit func(link storage.Link) error) error {
+	const fn = "pkg.storage.local_storage.links.EachLink"
+	err := s.walkLinks(func(_ string, link storage.Link) error {
+		if link.Owner != owner {
+			return nil
+		}
+		return visit(link)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteLink(ctx context.Context, domain, code string) error {
+	const fn = "pkg.storage.local_storage.links.DeleteLink"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := linkName(domain, code)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	if !s.fileExists(fileName) {
+		return fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+	}
+
+	if err = s.FileHandler.delete(fileName); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	// code may be taken again, new link must not inherit click history
+	if err = s.deleteClicks(domain, code); err != nil {
+		log.Warn(fmt.Sprintf("%s: failed to delete clicks", fn), "code", code, "error", err)
+	}
+
+	log.Debug("link deleted from local storage", "code", code)
+
+	return nil
+}
+
+// purgeExpiredLinks removes anonymous links whose expiry passed together
+// with their clicks. Expired owned links are kept, they answer 410 Gone and
+// their owner may still extend them and read their stats
+func (s *Storage) purgeExpiredLinks(now time.Time) int {
+	const fn = "pkg.storage.local_storage.links.purgeExpiredLinks"
+	purged := 0
+
+	purgeable := func(link storage.Link) bool {
+		return link.Owner == "" && link.Expired(now)
+	}
+
+	err := s.walkLinks(func(fileName string, link storage.Link) error {
+		if !purgeable(link) {
+			return nil
+		}
+
+		unlock := s.locks.lock(fileName)
+		defer unlock()
+
+		// link may have been updated since it was read
+		if current, err := s.readLink(fileName); err != nil || !purgeable(current) {
+			return nil
+		}
+		if err := s.FileHandler.delete(fileName); err != nil {
+			s.Log.Warn(fmt.Sprintf("%s: failed to delete expired link", fn), "file", fileName, "error", err)
+			return nil
+		}
+		purged++
+
+		if err := s.deleteClicks(link.Domain, link.Code); err != nil {
+			s.Log.Warn(fmt.Sprintf("%s: failed to delete clicks", fn), "code", link.Code, "error", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.Log.Warn(fmt.Sprintf("%s: %s", fn, err.Error()))
+	}
+
+	return purged
+}
+
+// walkLinks calls visit for every readable link of default and vanity
+// domains, walk stops at first error returned by visit
+func (s *Storage) walkLinks(visit func(fileName string, link storage.Link) error) error {
+	dirs := []string{LINKSDIR}
+	entries, err := os.ReadDir(filepath.Join(s.FileHandler.WorkDir, LINKSDIR))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(LINKSDIR, entry.Name()))
+		}
+	}
+
+	for _, dir := range dirs {
+		entries, err = os.ReadDir(filepath.Join(s.FileHandler.WorkDir, dir))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+				continue
+			}
+
+			fileName := filepath.Join(dir, name)
+			link, err := s.readLink(fileName)
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					s.Log.Warn("skipping unreadable link", "file", fileName, "error", err)
+				}
+				continue
+			}
+			if err = visit(fileName, link); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) readLink(fileName string) (storage.Link, error) {
+	file, err := s.FileHandler.load(fileName)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	var link storage.Link
+	if err = json.Unmarshal(payload, &link); err != nil {
+		return storage.Link{}, err
+	}
+	return link, nil
+}
+
+// linkName maps short code to file inside [LINKSDIR], links scoped to
+// vanity domain live in its subdirectory
+func linkName(domain, code string) (string, error) {
+	dir, err := scopeDir(LINKSDIR, domain)
+	if err != nil {
+		return "", err
+	}
+	if err = checkCode(code); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, code+".json"), nil
+}
+
+func scopeDir(base, domain string) (string, error) {
+	if domain == "" {
+		return base, nil
+	}
+	if err := checkDomain(domain); err != nil {
+		return "", err
+	}
+	return filepath.Join(base, domain), nil
+}
+
+// checkCode reports codes containing path elements as missing so they
+// never leave storage directory
+func checkCode(code string) error {
+	if code == "" || strings.ContainsAny(code, `/\.`) {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// checkDomain accepts lowercase host names only, which are safe directory names
+func checkDomain(domain string) error {
+	if strings.HasPrefix(domain, ".") || strings.Contains(domain, "..") {
+		return storage.ErrNotFound
+	}
+	for _, r := range domain {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '.' && r != '-' {
+			return storage.ErrNotFound
+		}
+	}
+	return nil
+}
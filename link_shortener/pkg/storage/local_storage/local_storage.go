@@ -1,32 +1,54 @@
 package local_storage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"io"
 	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// maxSlots bounds number of files sharing one FNV-32 name. Records whose
+// hashes collide go to <name>_<slot>.json, slot 0 keeps plain <name>.json
+const maxSlots = 8
+
+var errCollision = errors.New("too many hashes share file name")
+
+type Options struct {
+	// TTL of verification records, zero disables expiration
+	TTL time.Duration
+	// Fsync flushes every write to disk before reporting success
+	Fsync bool
+}
+
 type Storage struct {
 	FileHandler *Handler
 	Log         logger.Logger
+	TTL         time.Duration
+	locks       *keyLocks
 }
 
-func New(devEnv string, log logger.Logger) (*Storage, error) {
+// New creates file storage and removes temp files left by interrupted writes
+func New(devEnv string, opts Options, log logger.Logger) (*Storage, error) {
 	const fn = "pkg.storage.local_storage.local_storage.new"
 	s := &Storage{
-		Log: log,
+		Log:   log,
+		TTL:   opts.TTL,
+		locks: newKeyLocks(),
 	}
 
-	s.Log.With(fn)
-
-	fileHandler, err := newHandler(devEnv, s.Log)
+	fileHandler, err := newHandler(devEnv, opts.Fsync, s.Log)
 	if err != nil {
-		s.Log.Error(err.Error())
+		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
@@ -37,120 +59,237 @@ func New(devEnv string, log logger.Logger) (*Storage, error) {
 	return s, nil
 }
 
-func (s *Storage) Save(email string, hash string) error {
+func (s *Storage) Save(ctx context.Context, email string, hash string) error {
 	const fn = "pkg.storage.local_storage.local_storage.save"
-	s.Log.With(fn)
-	fileName, err := getName(hash, s.Log)
+	log := logger.FromContext(ctx, s.Log)
+	name, err := getName(hash, log)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if s.fileExists(fileName) {
-		s.Log.Warn(fmt.Sprintf("%s:%s already exists", fn, fileName))
-		return fmt.Errorf("%s:%s already exists", fn, fileName)
-	}
+	unlock := s.locks.lock(name)
+	defer unlock()
 
-	file, err := s.FileHandler.save(fileName)
+	fileName, free, err := s.findSlot(name, hash)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return fmt.Errorf("%s: %w", fn, err)
 	}
-	defer file.Close()
+	if !free {
+		log.Warn(fmt.Sprintf("%s:%s already exists", fn, fileName))
+		return fmt.Errorf("%s:%s %w", fn, fileName, storage.ErrAlreadyExists)
+	}
 
-	bin := newBin(email, hash)
+	bin := newBin(email, hash, s.TTL)
 
 	payload, err := json.Marshal(bin)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	_, err = file.Write(payload)
-	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+	if err = s.FileHandler.write(fileName, payload, true); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			log.Warn(fmt.Sprintf("%s:%s already exists", fn, fileName))
+			return fmt.Errorf("%s:%s %w", fn, fileName, storage.ErrAlreadyExists)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	s.Log.Debug("file saved to local storage")
+	log.Debug("file saved to local storage")
 
 	return nil
 }
 
-func (s *Storage) Load(hash string) (map[string]string, error) {
+func (s *Storage) Load(ctx context.Context, hash string) (map[string]string, error) {
 	const fn = "pkg.storage.local_storage.local_storage.load"
-	s.Log.With(fn)
-	details := make(map[string]string, 2)
-	fileName, err := getName(hash, s.Log)
+	log := logger.FromContext(ctx, s.Log)
+	details := make(map[string]string, 4)
+	name, err := getName(hash, log)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if !s.fileExists(fileName) {
-		s.Log.Warn(fmt.Sprintf("%s:%s does not exists", fn, fileName))
-		return nil, fmt.Errorf("%s:%s does not exists", fn, fileName)
-	}
+	unlock := s.locks.lock(name)
+	defer unlock()
 
-	file, err := s.FileHandler.load(fileName)
+	fileName, bin, err := s.lookup(name, hash)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		log.Warn(fmt.Sprintf("%s:%s %s", fn, name, err.Error()))
 		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	defer func() {
-		if err = file.Close(); err != nil {
-			s.Log.Error(fn, err.Error(), "error closing file")
-		}
-	}()
+	details[storage.KeyEmail] = bin.Email
+	details[storage.KeyHash] = bin.Hash
+	details[storage.KeyCreatedAt] = storage.FormatTime(bin.CreatedAt)
+	details[storage.KeyExpiresAt] = storage.FormatTime(bin.ExpiresAt)
 
-	payload, err := io.ReadAll(file)
+	log.Debug("file loaded from local storage", "file", fileName)
+
+	return details, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, hash string) error {
+	const fn = "link_shortener.pkg.storage.local_storage.local_storage.Delete"
+	log := logger.FromContext(ctx, s.Log)
+	name, err := getName(hash, log)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
-		return nil, fmt.Errorf("%s: %w", fn, err)
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	var bin Bin
-	err = json.Unmarshal(payload, &bin)
+	unlock := s.locks.lock(name)
+	defer unlock()
+
+	fileName, _, err := s.lookup(name, hash)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
-		return nil, fmt.Errorf("%s: %w", fn, err)
+		log.Warn(fmt.Sprintf("%s:%s %s", fn, name, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	details["email"] = bin.Email
-	details["hash"] = bin.Hash
+	err = s.FileHandler.delete(fileName)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
 
-	s.Log.Debug(fn, "file loaded from local storage")
+	log.Debug("file deleted from local storage")
+	return nil
+}
 
-	return details, nil
+// Ping checks that storage directory is writable
+func (s *Storage) Ping(_ context.Context) error {
+	const fn = "pkg.storage.local_storage.local_storage.Ping"
+	if err := s.FileHandler.probe(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return nil
 }
 
-func (s *Storage) Delete(hash string) error {
-	const fn = "link_shortener.pkg.storage.local_storage.local_storage.Delete"
-	s.Log.With(fn)
-	fileName, err := getName(hash, s.Log)
+// PurgeExpired removes verification records and anonymous links whose
+// expiry has passed and returns number of removed records
+func (s *Storage) PurgeExpired() (int, error) {
+	const fn = "pkg.storage.local_storage.local_storage.PurgeExpired"
+	entries, err := os.ReadDir(s.FileHandler.WorkDir)
 	if err != nil {
 		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
-		return fmt.Errorf("%s: %w", fn, err)
+		return 0, fmt.Errorf("%s: %w", fn, err)
 	}
 
-	if !s.fileExists(fileName) {
-		s.Log.Warn(fmt.Sprintf("%s:%s does not exists", fn, fileName))
-		return fmt.Errorf("%s:%s does not exists", fn, fileName)
+	now := time.Now().UTC()
+	purged := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isRecordName(entry.Name()) {
+			continue
+		}
+
+		if s.purgeIfExpired(entry.Name(), now) {
+			purged++
+		}
 	}
 
-	err = s.FileHandler.delete(fileName)
+	purged += s.purgeExpiredLinks(now)
+
+	s.Log.Debug("expired records purged from local storage", "count", purged)
+
+	return purged, nil
+}
+
+func (s *Storage) purgeIfExpired(fileName string, now time.Time) bool {
+	const fn = "pkg.storage.local_storage.local_storage.purgeIfExpired"
+	unlock := s.locks.lock(slotBase(fileName))
+	defer unlock()
+
+	bin, err := s.readBin(fileName)
 	if err != nil {
-		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
-		return fmt.Errorf("%s: %w", fn, err)
+		if !errors.Is(err, os.ErrNotExist) {
+			s.Log.Warn(fmt.Sprintf("%s: skipping unreadable record", fn), "file", fileName, "error", err)
+		}
+		return false
+	}
+	if !bin.expired(now) {
+		return false
 	}
 
-	s.Log.Debug("file deleted from local storage")
-	return nil
+	if err = s.FileHandler.delete(fileName); err != nil {
+		s.Log.Warn(fmt.Sprintf("%s: failed to delete expired record", fn), "file", fileName, "error", err)
+		return false
+	}
+	return true
 }
 
+// lookup finds slot holding record of hash, callers hold lock of name
+func (s *Storage) lookup(name, hash string) (string, *Bin, error) {
+	for slot := range maxSlots {
+		fileName := slotName(name, slot)
+		bin, err := s.readBin(fileName)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", nil, err
+		}
+		if bin.Hash == hash {
+			return fileName, bin, nil
+		}
+	}
+	return "", nil, storage.ErrNotFound
+}
+
+// findSlot returns first free slot for hash or slot already holding it,
+// callers hold lock of name
+func (s *Storage) findSlot(name, hash string) (string, bool, error) {
+	freeSlot := ""
+	for slot := range maxSlots {
+		fileName := slotName(name, slot)
+		bin, err := s.readBin(fileName)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				if freeSlot == "" {
+					freeSlot = fileName
+				}
+				continue
+			}
+			return "", false, err
+		}
+		if bin.Hash == hash {
+			return fileName, false, nil
+		}
+		if slot == 0 {
+			s.Log.Warn("file name collision detected", "name", name)
+		}
+	}
+	if freeSlot == "" {
+		return "", false, errCollision
+	}
+	return freeSlot, true, nil
+}
+
+func (s *Storage) readBin(fileName string) (*Bin, error) {
+	file, err := os.Open(filepath.Join(s.FileHandler.WorkDir, fileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var bin Bin
+	if err = json.Unmarshal(payload, &bin); err != nil {
+		return nil, err
+	}
+	return &bin, nil
+}
+
+// getName returns FNV-32 based file name of hash without extension,
+// distinct hashes may share it, see [maxSlots]
 func getName(hash string, log logger.Logger) (string, error) {
-	log.With("link_shortener.pkg.storage.local_storage.local_storage.getName()")
 	hasher := fnv.New32a()
 	_, err := hasher.Write([]byte(hash))
 	if err != nil {
@@ -161,7 +300,40 @@ func getName(hash string, log logger.Logger) (string, error) {
 
 	log.Debug(fmt.Sprintf("file name generated: %s.json", name))
 
-	return fmt.Sprintf("%s.json", name), nil
+	return name, nil
+}
+
+func slotName(name string, slot int) string {
+	if slot == 0 {
+		return name + ".json"
+	}
+	return fmt.Sprintf("%s_%d.json", name, slot)
+}
+
+// isRecordName reports whether fileName is named as verification record,
+// see [getName] and [slotName], other files are never touched by sweeps
+func isRecordName(fileName string) bool {
+	base, ok := strings.CutSuffix(fileName, ".json")
+	if !ok {
+		return false
+	}
+	name, slot, found := strings.Cut(base, "_")
+	if _, err := strconv.ParseUint(name, 10, 32); err != nil {
+		return false
+	}
+	if found {
+		n, err := strconv.Atoi(slot)
+		return err == nil && n > 0 && n < maxSlots
+	}
+	return true
+}
+
+func slotBase(fileName string) string {
+	base := strings.TrimSuffix(fileName, ".json")
+	if i := strings.LastIndex(base, "_"); i >= 0 {
+		return base[:i]
+	}
+	return base
 }
 
 func (s *Storage) fileExists(fileName string) bool {
@@ -0,0 +1,123 @@
+package local_storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (s *Storage) SaveOwnerKey(ctx context.Context, key storage.OwnerKey) error {
+	const fn = "pkg.storage.local_storage.owners.SaveOwnerKey"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := keyName(key.Hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	payload, err := json.Marshal(key)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = s.FileHandler.write(fileName, payload, true); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s: %w", fn, storage.ErrAlreadyExists)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("owner key saved to local storage")
+
+	return nil
+}
+
+func (s *Storage) LoadOwnerKey(ctx context.Context, hash string) (storage.OwnerKey, error) {
+	const fn = "pkg.storage.local_storage.owners.LoadOwnerKey"
+	log := logger.FromContext(ctx, s.Log)
+	fileName, err := keyName(hash)
+	if err != nil {
+		return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	file, err := os.Open(filepath.Join(s.FileHandler.WorkDir, fileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var key storage.OwnerKey
+	if err = json.Unmarshal(payload, &key); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return key, nil
+}
+
+// RevokeOwnerKeys deletes keys of email except the one with hash keep
+func (s *Storage) RevokeOwnerKeys(ctx context.Context, email, keep string) error {
+	const fn = "pkg.storage.local_storage.owners.RevokeOwnerKeys"
+	log := logger.FromContext(ctx, s.Log)
+	entries, err := os.ReadDir(filepath.Join(s.FileHandler.WorkDir, KEYSDIR))
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	revoked := 0
+	for _, entry := range entries {
+		hash, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok || hash == keep || checkCode(hash) != nil {
+			continue
+		}
+
+		key, err := s.LoadOwnerKey(ctx, hash)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		if key.Email != email {
+			continue
+		}
+
+		fileName, _ := keyName(hash)
+		if err = s.FileHandler.delete(fileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+		revoked++
+	}
+
+	log.Debug("owner keys revoked in local storage", "count", revoked)
+
+	return nil
+}
+
+// keyName maps hash of API key to file inside [KEYSDIR]
+func keyName(hash string) (string, error) {
+	if err := checkCode(hash); err != nil {
+		return "", err
+	}
+	return filepath.Join(KEYSDIR, hash+".json"), nil
+}
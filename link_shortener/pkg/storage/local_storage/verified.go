@@ -0,0 +1,123 @@
+package local_storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SaveVerifiedEmail adds email to registry or replaces its previous entry
+func (s *Storage) SaveVerifiedEmail(ctx context.Context, verified storage.VerifiedEmail) error {
+	const fn = "pkg.storage.local_storage.verified.SaveVerifiedEmail"
+	log := logger.FromContext(ctx, s.Log)
+	fileName := emailName(VERIFIEDDIR, verified.Email)
+
+	payload, err := json.Marshal(verified)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	if err = s.FileHandler.write(fileName, payload, false); err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("verified email saved to local storage")
+
+	return nil
+}
+
+func (s *Storage) LoadVerifiedEmail(ctx context.Context, email string) (storage.VerifiedEmail, error) {
+	const fn = "pkg.storage.local_storage.verified.LoadVerifiedEmail"
+	log := logger.FromContext(ctx, s.Log)
+	fileName := emailName(VERIFIEDDIR, email)
+
+	unlock := s.locks.lock(fileName)
+	defer unlock()
+
+	verified, err := s.readVerified(fileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return storage.VerifiedEmail{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.VerifiedEmail{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return verified, nil
+}
+
+// ListVerifiedEmails returns registry page ordered by email, non-positive
+// limit returns everything after offset
+func (s *Storage) ListVerifiedEmails(ctx context.Context, offset, limit int) ([]storage.VerifiedEmail, error) {
+	const fn = "pkg.storage.local_storage.verified.ListVerifiedEmails"
+	log := logger.FromContext(ctx, s.Log)
+	entries, err := os.ReadDir(filepath.Join(s.FileHandler.WorkDir, VERIFIEDDIR))
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	registry := make([]storage.VerifiedEmail, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		fileName := filepath.Join(VERIFIEDDIR, name)
+		verified, err := s.readVerified(fileName)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Warn("skipping unreadable verified email", "file", fileName, "error", err)
+			}
+			continue
+		}
+		registry = append(registry, verified)
+	}
+
+	sort.Slice(registry, func(i, j int) bool {
+		return registry[i].Email < registry[j].Email
+	})
+
+	if offset >= len(registry) {
+		return []storage.VerifiedEmail{}, nil
+	}
+	registry = registry[max(offset, 0):]
+	if limit > 0 && limit < len(registry) {
+		registry = registry[:limit]
+	}
+
+	return registry, nil
+}
+
+func (s *Storage) readVerified(fileName string) (storage.VerifiedEmail, error) {
+	file, err := os.Open(filepath.Join(s.FileHandler.WorkDir, fileName))
+	if err != nil {
+		return storage.VerifiedEmail{}, err
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		return storage.VerifiedEmail{}, err
+	}
+
+	var verified storage.VerifiedEmail
+	if err = json.Unmarshal(payload, &verified); err != nil {
+		return storage.VerifiedEmail{}, err
+	}
+	return verified, nil
+}
@@ -0,0 +1,70 @@
+package postgres_storage
+
+import (
+	"context"
+	"fmt"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"time"
+)
+
+// clicksBatchSize bounds rows inserted by single statement
+const clicksBatchSize = 500
+
+func (s *Storage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	const fn = "pkg.storage.postgres_storage.SaveClicks"
+	log := logger.FromContext(ctx, s.Log)
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	records := make([]Click, 0, len(clicks))
+	for _, click := range clicks {
+		records = append(records, Click{
+			Domain:    click.Domain,
+			Code:      click.Code,
+			Time:      click.Time,
+			Referrer:  click.Referrer,
+			UserAgent: click.UserAgent,
+			Country:   click.Country,
+			IPHash:    click.IPHash,
+		})
+	}
+
+	if err := s.DB.WithContext(ctx).CreateInBatches(records, clicksBatchSize).Error; err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("clicks saved to postgres storage", "count", len(clicks))
+
+	return nil
+}
+
+func (s *Storage) LoadClicks(ctx context.Context, domain, code string, from, to time.Time) ([]storage.Click, error) {
+	const fn = "pkg.storage.postgres_storage.LoadClicks"
+	log := logger.FromContext(ctx, s.Log)
+	var records []Click
+
+	err := s.DB.WithContext(ctx).Where("domain = ? AND code = ? AND time >= ? AND time < ?", domain, code, from, to).
+		Order("time").Find(&records).Error
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	clicks := make([]storage.Click, 0, len(records))
+	for _, r := range records {
+		clicks = append(clicks, storage.Click{
+			Domain:    r.Domain,
+			Code:      r.Code,
+			Time:      r.Time,
+			Referrer:  r.Referrer,
+			UserAgent: r.UserAgent,
+			Country:   r.Country,
+			IPHash:    r.IPHash,
+		})
+	}
+
+	return clicks, nil
+}
@@ -0,0 +1,65 @@
+package postgres_storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"time"
+)
+
+// SaveEmailIndex creates or replaces index entry of email
+func (s *Storage) SaveEmailIndex(ctx context.Context, index storage.EmailIndex) error {
+	const fn = "pkg.storage.postgres_storage.SaveEmailIndex"
+	log := logger.FromContext(ctx, s.Log)
+	record := &EmailIndex{
+		Email:   index.Email,
+		Hash:    index.Hash,
+		SentAt:  index.SentAt,
+		Resends: index.Resends,
+	}
+	if index.Verified() {
+		verifiedAt := index.VerifiedAt
+		record.VerifiedAt = &verifiedAt
+	}
+
+	err := s.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(record).Error
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("email index saved to postgres storage")
+
+	return nil
+}
+
+func (s *Storage) LoadEmailIndex(ctx context.Context, email string) (storage.EmailIndex, error) {
+	const fn = "pkg.storage.postgres_storage.LoadEmailIndex"
+	log := logger.FromContext(ctx, s.Log)
+	var record EmailIndex
+
+	if err := s.DB.WithContext(ctx).Where("email = ?", email).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return storage.EmailIndex{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.EmailIndex{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	index := storage.EmailIndex{
+		Email:   record.Email,
+		Hash:    record.Hash,
+		SentAt:  record.SentAt,
+		Resends: record.Resends,
+	}
+	if record.VerifiedAt != nil {
+		index.VerifiedAt = record.VerifiedAt.In(time.UTC)
+	}
+	return index, nil
+}
@@ -0,0 +1,228 @@
+package postgres_storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"time"
+)
+
+// linksBatchSize bounds rows read at once when links are streamed
+const linksBatchSize = 500
+
+func (s *Storage) SaveLink(ctx context.Context, link storage.Link) error {
+	const fn = "pkg.storage.postgres_storage.SaveLink"
+	log := logger.FromContext(ctx, s.Log)
+	record := newLinkRecord(link)
+
+	if err := s.DB.WithContext(ctx).Create(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Warn(fmt.Sprintf("%s: code already exists", fn), "code", link.Code)
+			return fmt.Errorf("%s: %w", fn, storage.ErrAlreadyExists)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("link saved to postgres storage", "code", link.Code)
+
+	return nil
+}
+
+func (s *Storage) LoadLink(ctx context.Context, domain, code string) (storage.Link, error) {
+	const fn = "pkg.storage.postgres_storage.LoadLink"
+	log := logger.FromContext(ctx, s.Log)
+	var record Link
+
+	if err := s.DB.WithContext(ctx).Where("domain = ? AND code = ?", domain, code).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return storage.Link{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.Link{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return record.toLink(), nil
+}
+
+// UpdateLink replaces stored link, link must already exist. Redirect
+// counter is owned by storage and kept as is
+func (s *Storage) UpdateLink(ctx context.Context, link storage.Link) error {
+	const fn = "pkg.storage.postgres_storage.UpdateLink"
+	log := logger.FromContext(ctx, s.Log)
+	record := newLinkRecord(link)
+
+	// map keeps zero values, e.g. re-enabled link or removed expiry
+	result := s.DB.WithContext(ctx).Model(&Link{}).
+		Where("domain = ? AND code = ?", link.Domain, link.Code).
+		Updates(map[string]any{
+			"url":           record.URL,
+			"owner":         record.Owner,
+			"disabled":      record.Disabled,
+			"max_clicks":    record.MaxClicks,
+			"password_hash": record.PasswordHash,
+			"expires_at":    record.ExpiresAt,
+		})
+	if result.Error != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, result.Error.Error()))
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+	}
+
+	log.Debug("link updated in postgres storage", "code", link.Code)
+
+	return nil
+}
+
+// CountRedirect increments redirect counter of link unless it reached
+// MaxClicks, in which case [storage.ErrLimitReached] is returned
+func (s *Storage) CountRedirect(ctx context.Context, domain, code string) error {
+	const fn = "pkg.storage.postgres_storage.CountRedirect"
+	log := logger.FromContext(ctx, s.Log)
+
+	// single statement keeps check and increment atomic under concurrent redirects
+	result := s.DB.WithContext(ctx).Model(&Link{}).
+		Where("domain = ? AND code = ? AND (max_clicks = 0 OR clicks < max_clicks)", domain, code).
+		UpdateColumn("clicks", gorm.Expr("clicks + 1"))
+	if result.Error != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, result.Error.Error()))
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	if _, err := s.LoadLink(ctx, domain, code); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	return fmt.Errorf("%s: %w", fn, storage.ErrLimitReached)
+}
+
+// ListLinks returns links created by owner, newest first
+func (s *Storage) ListLinks(ctx context.Context, owner string) ([]storage.Link, error) {
+	const fn = "pkg.storage.postgres_storage.ListLinks"
+	log := logger.FromContext(ctx, s.Log)
+	var records []Link
+
+	if err := s.DB.WithContext(ctx).Where("owner = ?", owner).Order("created_at DESC").Find(&records).Error; err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	links := make([]storage.Link, 0, len(records))
+	for _, record := range records {
+		links = append(links, record.toLink())
+	}
+
+	return links, nil
+}
+
+// EachLink calls visit for every link of owner reading them in batches,
+// iteration stops at first error returned by visit
+func (s *Storage) EachLink(ctx context.Context, owner string, visit func(link storage.Link) error) error {
+	const fn = "pkg.storage.postgres_storage.EachLink"
+	var records []Link
+
+	err := s.DB.WithContext(ctx).Where("owner = ?", owner).FindInBatches(&records, linksBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, record := range records {
+			if err := visit(record.toLink()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteLink(ctx context.Context, domain, code string) error {
+	const fn = "pkg.storage.postgres_storage.DeleteLink"
+	log := logger.FromContext(ctx, s.Log)
+
+	// code may be taken again, new link must not inherit click history
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("domain = ? AND code = ?", domain, code).Delete(&Link{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return storage.ErrNotFound
+		}
+		return tx.Where("domain = ? AND code = ?", domain, code).Delete(&Click{}).Error
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("link deleted from postgres storage", "domain", domain, "code", code)
+
+	return nil
+}
+
+// purgeExpiredLinks removes anonymous links whose expiry passed together
+// with their clicks. Expired owned links are kept, they answer 410 Gone and
+// their owner may still extend them and read their stats
+func (s *Storage) purgeExpiredLinks(now time.Time) (int, error) {
+	const expiredAnonymous = "owner = '' AND expires_at IS NOT NULL AND expires_at < ?"
+	var purged int64
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&Link{}).Select("domain, code").Where(expiredAnonymous, now)
+		if err := tx.Where("(domain, code) IN (?)", expired).Delete(&Click{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where(expiredAnonymous, now).Delete(&Link{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return int(purged), err
+}
+
+func newLinkRecord(link storage.Link) *Link {
+	record := &Link{
+		Domain:       link.Domain,
+		Code:         link.Code,
+		URL:          link.URL,
+		Owner:        link.Owner,
+		Disabled:     link.Disabled,
+		MaxClicks:    link.MaxClicks,
+		Clicks:       link.Clicks,
+		PasswordHash: link.PasswordHash,
+		CreatedAt:    link.CreatedAt,
+	}
+	if !link.ExpiresAt.IsZero() {
+		expiresAt := link.ExpiresAt
+		record.ExpiresAt = &expiresAt
+	}
+	return record
+}
+
+func (l Link) toLink() storage.Link {
+	link := storage.Link{
+		Domain:       l.Domain,
+		Code:         l.Code,
+		URL:          l.URL,
+		Owner:        l.Owner,
+		Disabled:     l.Disabled,
+		MaxClicks:    l.MaxClicks,
+		Clicks:       l.Clicks,
+		PasswordHash: l.PasswordHash,
+		CreatedAt:    l.CreatedAt,
+	}
+	if l.ExpiresAt != nil {
+		link.ExpiresAt = *l.ExpiresAt
+	}
+	return link
+}
@@ -0,0 +1,24 @@
+package postgres_storage
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// RunMigrations brings database schema in line with storage models
+func RunMigrations(db *gorm.DB) error {
+	models := []any{
+		&Verification{},
+		&Link{},
+		&Click{},
+		&OwnerKey{},
+		&EmailIndex{},
+		&VerifiedEmail{},
+	}
+
+	if err := db.AutoMigrate(models...); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package postgres_storage
+
+import "time"
+
+// Verification is a pending email verification record
+type Verification struct {
+	ID        uint       `gorm:"primaryKey"`
+	Email     string     `gorm:"not null;index"`
+	Hash      string     `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time  `gorm:"not null"`
+	ExpiresAt *time.Time `gorm:"index"`
+}
+
+// Link is a short link record, Domain and Code form primary key
+type Link struct {
+	Domain       string     `gorm:"primaryKey;default:''"`
+	Code         string     `gorm:"primaryKey"`
+	URL          string     `gorm:"not null"`
+	Owner        string     `gorm:"not null;default:'';index"`
+	Disabled     bool       `gorm:"not null;default:false"`
+	MaxClicks    int        `gorm:"not null;default:0"`
+	Clicks       int        `gorm:"not null;default:0"`
+	PasswordHash string     `gorm:"not null;default:''"`
+	CreatedAt    time.Time  `gorm:"not null"`
+	ExpiresAt    *time.Time `gorm:"index"`
+}
+
+// OwnerKey is API key of verified email, Hash is sha256 of the key
+type OwnerKey struct {
+	Hash      string    `gorm:"primaryKey;size:64"`
+	Email     string    `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// EmailIndex points email to hash of its latest verification record
+type EmailIndex struct {
+	Email      string     `gorm:"primaryKey"`
+	Hash       string     `gorm:"not null"`
+	SentAt     time.Time  `gorm:"not null"`
+	Resends    int        `gorm:"not null;default:0"`
+	VerifiedAt *time.Time `gorm:"index"`
+}
+
+// VerifiedEmail is registry entry of verified address
+type VerifiedEmail struct {
+	Email      string    `gorm:"primaryKey"`
+	VerifiedAt time.Time `gorm:"not null;index"`
+	IP         string    `gorm:"not null;default:''"`
+}
+
+// Click is a redirect event of short link
+type Click struct {
+	ID        uint      `gorm:"primaryKey"`
+	Domain    string    `gorm:"not null;default:'';index:idx_clicks_code_time"`
+	Code      string    `gorm:"not null;index:idx_clicks_code_time"`
+	Time      time.Time `gorm:"not null;index:idx_clicks_code_time"`
+	Referrer  string
+	UserAgent string
+	Country   string `gorm:"size:2"`
+	IPHash    string `gorm:"size:64"`
+}
@@ -0,0 +1,70 @@
+package postgres_storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+)
+
+func (s *Storage) SaveOwnerKey(ctx context.Context, key storage.OwnerKey) error {
+	const fn = "pkg.storage.postgres_storage.SaveOwnerKey"
+	log := logger.FromContext(ctx, s.Log)
+	record := &OwnerKey{
+		Hash:      key.Hash,
+		Email:     key.Email,
+		CreatedAt: key.CreatedAt,
+	}
+
+	if err := s.DB.WithContext(ctx).Create(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("%s: %w", fn, storage.ErrAlreadyExists)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("owner key saved to postgres storage")
+
+	return nil
+}
+
+func (s *Storage) LoadOwnerKey(ctx context.Context, hash string) (storage.OwnerKey, error) {
+	const fn = "pkg.storage.postgres_storage.LoadOwnerKey"
+	log := logger.FromContext(ctx, s.Log)
+	var record OwnerKey
+
+	if err := s.DB.WithContext(ctx).Where("hash = ?", hash).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.OwnerKey{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return storage.OwnerKey{
+		Hash:      record.Hash,
+		Email:     record.Email,
+		CreatedAt: record.CreatedAt,
+	}, nil
+}
+
+// RevokeOwnerKeys deletes keys of email except the one with hash keep
+func (s *Storage) RevokeOwnerKeys(ctx context.Context, email, keep string) error {
+	const fn = "pkg.storage.postgres_storage.RevokeOwnerKeys"
+	log := logger.FromContext(ctx, s.Log)
+
+	result := s.DB.WithContext(ctx).
+		Where("email = ? AND hash <> ?", email, keep).
+		Delete(&OwnerKey{})
+	if result.Error != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, result.Error.Error()))
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+
+	log.Debug("owner keys revoked in postgres storage", "count", result.RowsAffected)
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package postgres_storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	l "link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+	"time"
+)
+
+type Storage struct {
+	DB  *gorm.DB
+	Log l.Logger
+	TTL time.Duration
+}
+
+// New connects to postgres by dsn, verifies connection and applies migrations.
+// Verification records expire after ttl, zero ttl disables expiration
+func New(dsn string, ttl time.Duration, log l.Logger) (*Storage, error) {
+	const fn = "pkg.storage.postgres_storage.New"
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		log.Error("failed to connect to database", "error", err)
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	s := &Storage{
+		DB:  db,
+		Log: log,
+		TTL: ttl,
+	}
+
+	if err = s.Ping(context.Background()); err != nil {
+		log.Error("database healthcheck failed", "error", err)
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if err = RunMigrations(db); err != nil {
+		log.Error("database migrations failed", "error", err)
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("new postgres storage created")
+
+	return s, nil
+}
+
+func (s *Storage) Save(ctx context.Context, email string, hash string) error {
+	const fn = "pkg.storage.postgres_storage.Save"
+	log := l.FromContext(ctx, s.Log)
+	now := time.Now().UTC()
+	record := &Verification{
+		Email:     email,
+		Hash:      hash,
+		CreatedAt: now,
+	}
+	if s.TTL > 0 {
+		expiresAt := now.Add(s.TTL)
+		record.ExpiresAt = &expiresAt
+	}
+
+	if err := s.DB.WithContext(ctx).Create(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Warn(fmt.Sprintf("%s: hash already exists", fn))
+			return fmt.Errorf("%s: %w", fn, storage.ErrAlreadyExists)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("verification saved to postgres storage")
+
+	return nil
+}
+
+func (s *Storage) Load(ctx context.Context, hash string) (map[string]string, error) {
+	const fn = "pkg.storage.postgres_storage.Load"
+	log := l.FromContext(ctx, s.Log)
+	var record Verification
+
+	if err := s.DB.WithContext(ctx).Where("hash = ?", hash).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn(fmt.Sprintf("%s: hash does not exist", fn))
+			return nil, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	details := map[string]string{
+		storage.KeyEmail:     record.Email,
+		storage.KeyHash:      record.Hash,
+		storage.KeyCreatedAt: storage.FormatTime(record.CreatedAt),
+		storage.KeyExpiresAt: "",
+	}
+	if record.ExpiresAt != nil {
+		details[storage.KeyExpiresAt] = storage.FormatTime(*record.ExpiresAt)
+	}
+
+	log.Debug("verification loaded from postgres storage")
+
+	return details, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, hash string) error {
+	const fn = "pkg.storage.postgres_storage.Delete"
+	log := l.FromContext(ctx, s.Log)
+
+	result := s.DB.WithContext(ctx).Where("hash = ?", hash).Delete(&Verification{})
+	if result.Error != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, result.Error.Error()))
+		return fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Warn(fmt.Sprintf("%s: hash does not exist", fn))
+		return fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+	}
+
+	log.Debug("verification deleted from postgres storage")
+
+	return nil
+}
+
+// PurgeExpired removes verification records and anonymous links whose
+// expiry has passed and returns number of removed records
+func (s *Storage) PurgeExpired() (int, error) {
+	const fn = "pkg.storage.postgres_storage.PurgeExpired"
+	now := time.Now().UTC()
+
+	result := s.DB.Where("expires_at IS NOT NULL AND expires_at < ?", now).
+		Delete(&Verification{})
+	if result.Error != nil {
+		s.Log.Error(fmt.Sprintf("%s: %s", fn, result.Error.Error()))
+		return 0, fmt.Errorf("%s: %w", fn, result.Error)
+	}
+	purged := int(result.RowsAffected)
+
+	links, err := s.purgeExpiredLinks(now)
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return purged, fmt.Errorf("%s: %w", fn, err)
+	}
+	purged += links
+
+	s.Log.Debug("expired records purged from postgres storage", "count", purged)
+
+	return purged, nil
+}
+
+// Ping checks that database connection is alive
+func (s *Storage) Ping(ctx context.Context) error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close releases underlying connection pool
+func (s *Storage) Close() error {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,81 @@
+package postgres_storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"link_shortener/pkg/logger"
+	"link_shortener/pkg/storage"
+)
+
+// SaveVerifiedEmail adds email to registry or replaces its previous entry
+func (s *Storage) SaveVerifiedEmail(ctx context.Context, verified storage.VerifiedEmail) error {
+	const fn = "pkg.storage.postgres_storage.SaveVerifiedEmail"
+	log := logger.FromContext(ctx, s.Log)
+	record := &VerifiedEmail{
+		Email:      verified.Email,
+		VerifiedAt: verified.VerifiedAt,
+		IP:         verified.IP,
+	}
+
+	err := s.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(record).Error
+	if err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	log.Debug("verified email saved to postgres storage")
+
+	return nil
+}
+
+func (s *Storage) LoadVerifiedEmail(ctx context.Context, email string) (storage.VerifiedEmail, error) {
+	const fn = "pkg.storage.postgres_storage.LoadVerifiedEmail"
+	log := logger.FromContext(ctx, s.Log)
+	var record VerifiedEmail
+
+	if err := s.DB.WithContext(ctx).Where("email = ?", email).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return storage.VerifiedEmail{}, fmt.Errorf("%s: %w", fn, storage.ErrNotFound)
+		}
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return storage.VerifiedEmail{}, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	return newVerifiedEmail(record), nil
+}
+
+// ListVerifiedEmails returns registry page ordered by email, non-positive
+// limit returns everything after offset
+func (s *Storage) ListVerifiedEmails(ctx context.Context, offset, limit int) ([]storage.VerifiedEmail, error) {
+	const fn = "pkg.storage.postgres_storage.ListVerifiedEmails"
+	log := logger.FromContext(ctx, s.Log)
+	var records []VerifiedEmail
+
+	query := s.DB.WithContext(ctx).Order("email").Offset(max(offset, 0))
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		log.Error(fmt.Sprintf("%s: %s", fn, err.Error()))
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+
+	registry := make([]storage.VerifiedEmail, 0, len(records))
+	for _, record := range records {
+		registry = append(registry, newVerifiedEmail(record))
+	}
+	return registry, nil
+}
+
+func newVerifiedEmail(record VerifiedEmail) storage.VerifiedEmail {
+	return storage.VerifiedEmail{
+		Email:      record.Email,
+		VerifiedAt: record.VerifiedAt,
+		IP:         record.IP,
+	}
+}
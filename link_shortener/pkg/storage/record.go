@@ -0,0 +1,33 @@
+package storage
+
+import "time"
+
+// Keys of verification record details returned by storage Load
+const (
+	KeyEmail     = "email"
+	KeyHash      = "hash"
+	KeyCreatedAt = "created_at"
+	KeyExpiresAt = "expires_at"
+)
+
+// FormatTime renders record timestamps, zero time is rendered as empty string
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Expired reports whether record details carry expiry which already passed.
+// Records without expiry never expire, unparsable expiry is treated as expired
+func Expired(details map[string]string, now time.Time) bool {
+	raw := details[KeyExpiresAt]
+	if raw == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return true
+	}
+	return now.After(expiresAt)
+}
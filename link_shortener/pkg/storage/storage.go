@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("record not found")
+	ErrAlreadyExists = errors.New("record already exists")
+	ErrLimitReached  = errors.New("record limit reached")
+)
+
+// Link is a persisted short link: Code is the public path segment
+// and URL is the target the client is redirected to. Domain scopes
+// code to vanity host, empty Domain is the default one. Owner is
+// verified email of creator, anonymous links have none and expire
+// at ExpiresAt. Disabled links are kept but not redirected. Links
+// with MaxClicks stop redirecting once Clicks reaches it, links with
+// PasswordHash redirect only after password is entered
+type Link struct {
+	Domain       string    `json:"domain,omitempty"`
+	Code         string    `json:"code"`
+	URL          string    `json:"url"`
+	Owner        string    `json:"owner,omitempty"`
+	Disabled     bool      `json:"disabled,omitempty"`
+	MaxClicks    int       `json:"max_clicks,omitempty"`
+	Clicks       int       `json:"clicks,omitempty"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// Expired reports whether link has expiry which passed at now
+func (l Link) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// Exhausted reports whether link used up its redirects
+func (l Link) Exhausted() bool {
+	return l.MaxClicks > 0 && l.Clicks >= l.MaxClicks
+}
+
+// OwnerKey is API key issued to verified email, only hash of the key is kept
+type OwnerKey struct {
+	Hash      string    `json:"hash"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EmailIndex points email to hash of its latest verification record so
+// the record can be found again without the link. SentAt is when the link
+// was last emailed and VerifiedAt is set once email is verified
+type EmailIndex struct {
+	Email      string    `json:"email"`
+	Hash       string    `json:"hash"`
+	SentAt     time.Time `json:"sent_at"`
+	Resends    int       `json:"resends,omitempty"`
+	VerifiedAt time.Time `json:"verified_at,omitzero"`
+}
+
+// Verified reports whether email of index entry has been verified
+func (e EmailIndex) Verified() bool {
+	return !e.VerifiedAt.IsZero()
+}
+
+// VerifiedEmail is address which has proven ownership by following its
+// verification link. VerifiedAt and IP describe the latest verification
+type VerifiedEmail struct {
+	Email      string    `json:"email"`
+	VerifiedAt time.Time `json:"verified_at"`
+	IP         string    `json:"ip,omitempty"`
+}
+
+// Click is a single redirect of short link. IPHash is salted hash of client
+// IP so visitors can be counted without keeping addresses
+type Click struct {
+	Domain    string    `json:"domain,omitempty"`
+	Code      string    `json:"code"`
+	Time      time.Time `json:"time"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	IPHash    string    `json:"ip_hash,omitempty"`
+}
@@ -1,10 +1,50 @@
 package validator
 
-import "github.com/go-playground/validator/v10"
+import (
+	"github.com/go-playground/validator/v10"
+	"regexp"
+	"strings"
+)
 
-type StructValidator struct{}
+// DefaultReserved are aliases shadowing service routes, they can never be taken
+var DefaultReserved = []string{
+	"api", "send", "verify", "health", "info", "metrics",
+	"livez", "readyz", "static", "admin",
+}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+type StructValidator struct {
+	// Reserved extends [DefaultReserved], words are compared case-insensitively
+	Reserved []string
+}
 
 func (s StructValidator) Validate(str any) error {
 	validate := validator.New()
+	if err := validate.RegisterValidation("alias", s.validateAlias); err != nil {
+		return err
+	}
 	return validate.Struct(str)
 }
+
+// IsReserved reports whether word may not be used as short code
+func (s StructValidator) IsReserved(word string) bool {
+	for _, reserved := range DefaultReserved {
+		if strings.EqualFold(word, reserved) {
+			return true
+		}
+	}
+	for _, reserved := range s.Reserved {
+		if strings.EqualFold(word, strings.TrimSpace(reserved)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAlias implements "alias" tag: letters, digits, dash and underscore,
+// starting with letter or digit, and not reserved
+func (s StructValidator) validateAlias(fl validator.FieldLevel) bool {
+	alias := fl.Field().String()
+	return aliasPattern.MatchString(alias) && !s.IsReserved(alias)
+}